@@ -0,0 +1,404 @@
+// Package caption parses the captions of word posts.
+//
+// The first line of a caption is the word and the lines after it are its
+// meaning. After the meaning come optional labelled fields, one per line:
+//
+//	apple
+//	a round fruit with red or green skin
+//	pos: noun
+//	ipa: /ˈæp.əl/
+//	syn: pome
+//	ant: none
+//	ex: An apple a day keeps the doctor away.
+//	tags: food, fruit
+//	lang: en, fa
+//
+// syn, ant and tags are comma separated lists and ex is a single example, all
+// of them may be repeated. pos, ipa and lang may only be given once. lang is the
+// BCP-47 tag of the language of the word, optionally followed by the one of its
+// meaning. Labels are case insensitive and blank lines are ignored.
+//
+// A word with several senses numbers them. pos and ex after a sense belong to
+// it and may be given once per sense, the other fields are the word's:
+//
+//	bank
+//	1. the land beside a river
+//	pos: noun
+//	ex: We had a picnic on the bank.
+//	2. to keep money in a bank
+//	pos: verb
+//	ipa: /bæŋk/
+package caption
+
+import (
+	"fmt"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
+	"regexp"
+	"strings"
+)
+
+const (
+	FieldPartOfSpeech = "pos"
+	FieldIPA          = "ipa"
+	FieldSynonyms     = "syn"
+	FieldAntonyms     = "ant"
+	FieldExample      = "ex"
+	FieldTags         = "tags"
+	FieldLanguage     = "lang"
+)
+
+var fields = []string{FieldPartOfSpeech, FieldIPA, FieldSynonyms, FieldAntonyms, FieldExample, FieldTags, FieldLanguage}
+
+// senseNumber matches the number a sense starts with.
+var senseNumber = regexp.MustCompile(`^\d+\.\s+`)
+
+type Caption struct {
+	Word string
+	// Meaning is the whole meaning, with every sense numbered if there is more
+	// than one.
+	Meaning      string
+	PartOfSpeech string
+	IPA          string
+	Synonyms     []string
+	Antonyms     []string
+	Examples     []string
+	Tags         []string
+	// Senses is only set for a word with more than one sense.
+	Senses []Sense
+	// SourceLang and TargetLang are the BCP-47 tags given by lang, empty if
+	// it wasn't.
+	SourceLang string
+	TargetLang string
+}
+
+// Sense is one of the numbered meanings of a word.
+type Sense struct {
+	Definition   string
+	PartOfSpeech string
+	Example      string
+}
+
+// Error is a line of a caption that could not be parsed. Line starts at 1.
+type Error struct {
+	Line   int
+	Text   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+	}
+
+	return fmt.Sprintf("line %d: %s: %q", e.Line, e.Reason, e.Text)
+}
+
+// Parse parses caption as described in the package documentation. The word is
+// kept as written, it is up to the caller to lower case it for its language.
+// Errors are of type *Error.
+func Parse(caption string) (*Caption, error) {
+	lines := strings.Split(strings.ReplaceAll(caption, "\r\n", "\n"), "\n")
+
+	res := &Caption{Word: strings.TrimSpace(lines[0])}
+	if res.Word == "" {
+		return nil, &Error{Line: 1, Reason: "the first line must be the word"}
+	}
+
+	var (
+		meaning  []string
+		inFields bool
+		numbered bool
+	)
+	for i, line := range lines[1:] {
+		lineNo := i + 2
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if loc := senseNumber.FindStringIndex(line); loc != nil && (numbered || len(meaning) == 0) {
+			numbered, inFields = true, false
+			res.Senses = append(res.Senses, Sense{Definition: line[loc[1]:]})
+			meaning = append(meaning, line)
+			continue
+		}
+
+		label, value, ok := splitField(line)
+		if !ok {
+			if inFields && numbered {
+				return nil, &Error{Line: lineNo, Text: line, Reason: fmt.Sprintf("expected a field, one of %s, or the next sense", strings.Join(fields, ", "))}
+			} else if inFields {
+				return nil, &Error{Line: lineNo, Text: line, Reason: fmt.Sprintf("expected a field, one of %s", strings.Join(fields, ", "))}
+			}
+			if numbered {
+				sense := &res.Senses[len(res.Senses)-1]
+				sense.Definition += "\n" + line
+			}
+			meaning = append(meaning, line)
+			continue
+		}
+
+		if len(meaning) == 0 {
+			return nil, &Error{Line: lineNo, Text: line, Reason: "the meaning must come before the fields"}
+		}
+		inFields = true
+
+		if value == "" {
+			return nil, &Error{Line: lineNo, Text: line, Reason: fmt.Sprintf("%s has no value", label)}
+		}
+
+		var sense *Sense
+		if numbered {
+			sense = &res.Senses[len(res.Senses)-1]
+		}
+
+		switch {
+		case label == FieldPartOfSpeech && sense != nil:
+			if sense.PartOfSpeech != "" {
+				return nil, &Error{Line: lineNo, Text: line, Reason: fmt.Sprintf("pos is given twice for sense %d", len(res.Senses))}
+			}
+			sense.PartOfSpeech = strings.ToLower(value)
+		case label == FieldExample && sense != nil:
+			if sense.Example != "" {
+				return nil, &Error{Line: lineNo, Text: line, Reason: fmt.Sprintf("ex is given twice for sense %d", len(res.Senses))}
+			}
+			sense.Example = value
+		case label == FieldPartOfSpeech:
+			if res.PartOfSpeech != "" {
+				return nil, &Error{Line: lineNo, Text: line, Reason: "pos is given twice"}
+			}
+			res.PartOfSpeech = strings.ToLower(value)
+		case label == FieldIPA:
+			if res.IPA != "" {
+				return nil, &Error{Line: lineNo, Text: line, Reason: "ipa is given twice"}
+			}
+			res.IPA = value
+		case label == FieldSynonyms:
+			res.Synonyms = append(res.Synonyms, splitList(value)...)
+		case label == FieldAntonyms:
+			res.Antonyms = append(res.Antonyms, splitList(value)...)
+		case label == FieldExample:
+			res.Examples = append(res.Examples, value)
+		case label == FieldTags:
+			res.Tags = append(res.Tags, splitList(strings.ToLower(value))...)
+		case label == FieldLanguage:
+			if res.SourceLang != "" {
+				return nil, &Error{Line: lineNo, Text: line, Reason: "lang is given twice"}
+			}
+			tags := splitList(value)
+			if len(tags) > 2 {
+				return nil, &Error{Line: lineNo, Text: line, Reason: "lang is the language of the word and optionally of its meaning"}
+			}
+			for i, s := range tags {
+				tag, err := lang.Parse(s)
+				if err != nil {
+					return nil, &Error{Line: lineNo, Text: line, Reason: fmt.Sprintf("%q is not a language tag", s)}
+				}
+				tags[i] = tag.String()
+			}
+			res.SourceLang = tags[0]
+			if len(tags) == 2 {
+				res.TargetLang = tags[1]
+			}
+		}
+	}
+
+	if len(meaning) == 0 {
+		return nil, &Error{Line: 2, Reason: "the second line must be the meaning"}
+	}
+	res.Meaning = strings.Join(meaning, "\n")
+
+	switch {
+	case len(res.Senses) == 1:
+		// a single numbered sense is just the meaning.
+		sense := res.Senses[0]
+		res.Meaning, res.PartOfSpeech, res.Senses = sense.Definition, sense.PartOfSpeech, nil
+		if sense.Example != "" {
+			res.Examples = []string{sense.Example}
+		}
+	case len(res.Senses) > 1:
+		definitions := make([]string, 0, len(res.Senses))
+		for i, sense := range res.Senses {
+			definitions = append(definitions, fmt.Sprintf("%d. %s", i+1, sense.Definition))
+		}
+		res.Meaning = strings.Join(definitions, "\n")
+	}
+
+	return res, nil
+}
+
+// splitField splits "label: value" if label is one of fields.
+func splitField(line string) (string, string, bool) {
+	label, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	label = strings.ToLower(strings.TrimSpace(label))
+	for _, field := range fields {
+		if field == label {
+			return label, strings.TrimSpace(value), true
+		}
+	}
+
+	return "", "", false
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// Entry is one word of a list. Line is where it starts in the list, Err is set
+// instead of Caption if it could not be parsed.
+type Entry struct {
+	Line    int
+	Caption *Caption
+	Err     error
+}
+
+// lineSeparators split a "word - meaning" line.
+var lineSeparators = []string{" - ", " – ", " — "}
+
+// ParseList parses a message holding several words. Blocks are separated by
+// blank lines. A block whose first line looks like "word - meaning" has one
+// word per line, any other block is a single caption. A block starting with a
+// field or a numbered sense belongs to the caption before it, so captions can
+// still have blank lines before their fields and between their senses.
+func ParseList(text string) []Entry {
+	type block struct {
+		start int
+		lines []string
+		list  bool
+	}
+
+	var (
+		lines  = strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+		blocks []*block
+		cur    *block
+	)
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			cur = nil
+			continue
+		}
+
+		if cur == nil {
+			_, _, isField := splitField(line)
+			isSense := senseNumber.MatchString(strings.TrimSpace(line))
+			if last := len(blocks) - 1; (isField || isSense) && last >= 0 && !blocks[last].list {
+				cur = blocks[last]
+				cur.lines = append([]string(nil), lines[cur.start-1:i]...)
+			} else {
+				_, _, isList := splitLine(line)
+				cur = &block{start: i + 1, list: isList}
+				blocks = append(blocks, cur)
+			}
+		}
+		cur.lines = append(cur.lines, line)
+	}
+
+	var entries []Entry
+	for _, b := range blocks {
+		if b.list {
+			for i, line := range b.lines {
+				entries = append(entries, parseLine(b.start+i, line))
+			}
+			continue
+		}
+
+		c, err := Parse(strings.Join(b.lines, "\n"))
+		if e, ok := err.(*Error); ok {
+			e.Line += b.start - 1
+		}
+		entries = append(entries, Entry{Line: b.start, Caption: c, Err: err})
+	}
+
+	return entries
+}
+
+func parseLine(lineNo int, line string) Entry {
+	word, meaning, ok := splitLine(line)
+	if !ok {
+		return Entry{Line: lineNo, Err: &Error{Line: lineNo, Text: strings.TrimSpace(line), Reason: "expected word - meaning"}}
+	}
+
+	return Entry{Line: lineNo, Caption: &Caption{Word: word, Meaning: meaning}}
+}
+
+// splitLine splits "word - meaning" at the first separator.
+func splitLine(line string) (string, string, bool) {
+	at, sep := -1, ""
+	for _, s := range lineSeparators {
+		if i := strings.Index(line, s); i >= 0 && (at < 0 || i < at) {
+			at, sep = i, s
+		}
+	}
+	if at < 0 {
+		return "", "", false
+	}
+
+	word := strings.TrimSpace(line[:at])
+	meaning := strings.TrimSpace(line[at+len(sep):])
+	if word == "" || meaning == "" {
+		return "", "", false
+	}
+
+	return word, meaning, true
+}
+
+// Format writes c back in the caption format, so it can be edited and parsed
+// again.
+func Format(c *Caption) string {
+	lines := []string{c.Word}
+	senses := len(c.Senses) > 1
+	if senses {
+		for i, sense := range c.Senses {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, sense.Definition))
+			if sense.PartOfSpeech != "" {
+				lines = append(lines, FieldPartOfSpeech+": "+sense.PartOfSpeech)
+			}
+			if sense.Example != "" {
+				lines = append(lines, FieldExample+": "+sense.Example)
+			}
+		}
+	} else {
+		lines = append(lines, c.Meaning)
+	}
+	// pos and examples after the senses would belong to the last one.
+	if c.PartOfSpeech != "" && !senses {
+		lines = append(lines, FieldPartOfSpeech+": "+c.PartOfSpeech)
+	}
+	if c.IPA != "" {
+		lines = append(lines, FieldIPA+": "+c.IPA)
+	}
+	if len(c.Synonyms) > 0 {
+		lines = append(lines, FieldSynonyms+": "+strings.Join(c.Synonyms, ", "))
+	}
+	if len(c.Antonyms) > 0 {
+		lines = append(lines, FieldAntonyms+": "+strings.Join(c.Antonyms, ", "))
+	}
+	if !senses {
+		for _, example := range c.Examples {
+			lines = append(lines, FieldExample+": "+example)
+		}
+	}
+	if len(c.Tags) > 0 {
+		lines = append(lines, FieldTags+": "+strings.Join(c.Tags, ", "))
+	}
+	if c.SourceLang != "" {
+		language := c.SourceLang
+		if c.TargetLang != "" {
+			language += ", " + c.TargetLang
+		}
+		lines = append(lines, FieldLanguage+": "+language)
+	}
+
+	return strings.Join(lines, "\n")
+}
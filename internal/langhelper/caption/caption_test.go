@@ -0,0 +1,134 @@
+package caption
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		caption string
+		want    *Caption
+	}{
+		{
+			name:    "word and meaning",
+			caption: "Apple\na round fruit",
+			want:    &Caption{Word: "Apple", Meaning: "a round fruit"},
+		},
+		{
+			name:    "languages",
+			caption: "Haus\nhouse\nlang: de, en",
+			want:    &Caption{Word: "Haus", Meaning: "house", SourceLang: "de", TargetLang: "en"},
+		},
+		{
+			name:    "meaning on several lines",
+			caption: "bank\r\nthe land beside a river\r\n\r\na place that keeps money",
+			want:    &Caption{Word: "bank", Meaning: "the land beside a river\na place that keeps money"},
+		},
+		{
+			name: "every field",
+			caption: "apple\na round fruit\nPOS: Noun\nipa: /ˈæp.əl/\nsyn: pome\nant: none\n" +
+				"ex: An apple a day.\nex: Apples are red.\ntags: Food, fruit,\nsyn: malus",
+			want: &Caption{
+				Word:         "apple",
+				Meaning:      "a round fruit",
+				PartOfSpeech: "noun",
+				IPA:          "/ˈæp.əl/",
+				Synonyms:     []string{"pome", "malus"},
+				Antonyms:     []string{"none"},
+				Examples:     []string{"An apple a day.", "Apples are red."},
+				Tags:         []string{"food", "fruit"},
+			},
+		},
+		{
+			name:    "colon in the meaning",
+			caption: "ratio\nnote: a relation between two numbers",
+			want:    &Caption{Word: "ratio", Meaning: "note: a relation between two numbers"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.caption)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("Parse() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		caption string
+		line    int
+	}{
+		{name: "no word", caption: "\na round fruit", line: 1},
+		{name: "no meaning", caption: "apple", line: 2},
+		{name: "field before the meaning", caption: "apple\npos: noun\na round fruit", line: 2},
+		{name: "meaning after the fields", caption: "apple\na round fruit\npos: noun\nred or green", line: 4},
+		{name: "empty field", caption: "apple\na round fruit\nsyn:", line: 3},
+		{name: "pos twice", caption: "apple\na round fruit\npos: noun\npos: verb", line: 4},
+		{name: "ipa twice", caption: "apple\na round fruit\nipa: a\nipa: b", line: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.caption)
+			e, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Parse() error = %v, want an *Error", err)
+			}
+			if e.Line != test.line {
+				t.Fatalf("error at line %d, want %d: %v", e.Line, test.line, e)
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		words []string
+	}{
+		{"one line", "apple - a fruit", []string{"apple"}},
+		{"several lines", "apple - a fruit\nbank – the land beside a river\ncar — a vehicle", []string{"apple", "bank", "car"}},
+		{"caption", "apple\na round fruit\npos: noun", []string{"apple"}},
+		{"captions", "apple\na round fruit\n\nbank\nthe land beside a river", []string{"apple", "bank"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := ParseList(tt.text)
+			if len(entries) != len(tt.words) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(tt.words))
+			}
+			for i, e := range entries {
+				if e.Err != nil {
+					t.Fatalf("entry %d: %v", i, e.Err)
+				}
+				if e.Caption.Word != tt.words[i] {
+					t.Errorf("entry %d is %q, want %q", i, e.Caption.Word, tt.words[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseListLineNumbers(t *testing.T) {
+	entries := ParseList("apple - a fruit\n\nbank\nthe land beside a river\npos: noun\noops")
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].Line != 1 || entries[1].Line != 3 {
+		t.Errorf("entries start at lines %d and %d, want 1 and 3", entries[0].Line, entries[1].Line)
+	}
+	e, ok := entries[1].Err.(*Error)
+	if !ok || e.Line != 6 {
+		t.Errorf("got error %v, want one at line 6", entries[1].Err)
+	}
+}
@@ -0,0 +1,51 @@
+package charts
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+const (
+	barWidth  = 14
+	barGap    = 4
+	barHeight = 160
+)
+
+// Forecast draws how many cards are due on each day starting with start.
+// Overdue cards should be counted on the first day.
+func Forecast(counts []int, start time.Time) *image.RGBA {
+	var (
+		step   = barWidth + barGap
+		left   = padding + 4*charWidth
+		top    = padding + 2*lineHeight
+		width  = left + len(counts)*step + padding
+		height = top + barHeight + 2*lineHeight + padding
+		img    = newCanvas(width, height)
+		most   = 0
+	)
+	for _, c := range counts {
+		most = max(most, c)
+	}
+
+	drawText(img, padding, padding+lineHeight-3, foreground, fmt.Sprintf("Due in the next %d days", len(counts)))
+
+	base := top + barHeight
+	drawText(img, padding, top+lineHeight-3, muted, fmt.Sprint(most))
+	drawText(img, padding, base, muted, "0")
+	drawLine(img, left, base, width-padding, base, grid)
+
+	for i, c := range counts {
+		x := left + i*step
+		if c > 0 {
+			h := max(c*barHeight/most, 1)
+			fillRect(img, image.Rect(x, base-h, x+barWidth, base), accent)
+		}
+
+		if i%7 == 0 {
+			drawText(img, x, base+lineHeight+2, muted, start.AddDate(0, 0, i).Format("Jan 2"))
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,100 @@
+// Package charts renders the progress charts sent by /stats chart. It only uses
+// the standard image packages and the fixed size font of x/image, so nothing
+// has to be fetched to draw them.
+package charts
+
+import (
+	"bytes"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+const (
+	padding    = 16
+	lineHeight = 13
+	charWidth  = 7
+)
+
+var (
+	background = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	foreground = color.RGBA{R: 0x24, G: 0x29, B: 0x2f, A: 0xff}
+	muted      = color.RGBA{R: 0x8c, G: 0x95, B: 0x9f, A: 0xff}
+	grid       = color.RGBA{R: 0xea, G: 0xee, B: 0xf2, A: 0xff}
+	accent     = color.RGBA{R: 0x40, G: 0xc4, B: 0x63, A: 0xff}
+)
+
+// Encode returns img as PNG.
+func Encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newCanvas(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	return img
+}
+
+// drawText draws s with its baseline starting at x, y.
+func drawText(img *image.RGBA, x, y int, c color.Color, s string) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+func textWidth(s string) int {
+	return len([]rune(s)) * charWidth
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// drawLine draws a line from (x0, y0) to (x1, y1) with Bresenham's algorithm.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		if e2 := 2 * e; e2 >= dy {
+			e += dy
+			x0 += sx
+		} else {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
@@ -0,0 +1,71 @@
+package charts
+
+import (
+	"image"
+	"image/color"
+	"time"
+)
+
+const (
+	heatmapWeeks = 53
+	cellSize     = 11
+	cellGap      = 3
+)
+
+var heatmapLevels = []color.RGBA{
+	{R: 0xeb, G: 0xed, B: 0xf0, A: 0xff},
+	{R: 0x9b, G: 0xe9, B: 0xa8, A: 0xff},
+	{R: 0x40, G: 0xc4, B: 0x63, A: 0xff},
+	{R: 0x30, G: 0xa1, B: 0x4e, A: 0xff},
+	{R: 0x21, G: 0x6e, B: 0x39, A: 0xff},
+}
+
+// Heatmap draws the reviews of the last year GitHub style, one column per week
+// ending with the week of today. counts is keyed by time.DateOnly dates.
+func Heatmap(counts map[string]int, today time.Time) *image.RGBA {
+	var (
+		step   = cellSize + cellGap
+		left   = padding + 3*charWidth + cellGap
+		top    = padding + 2*lineHeight
+		width  = left + heatmapWeeks*step + padding
+		height = top + 7*step + padding
+		img    = newCanvas(width, height)
+	)
+
+	drawText(img, padding, padding+lineHeight-3, foreground, "Reviews in the last year")
+	for i, label := range []string{"Mon", "Wed", "Fri"} {
+		drawText(img, padding, top+(2*i+1)*step+cellSize-1, muted, label)
+	}
+
+	var (
+		today0 = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+		start  = today0.AddDate(0, 0, -int(today0.Weekday())-(heatmapWeeks-1)*7)
+		most   = 0
+	)
+	for _, c := range counts {
+		most = max(most, c)
+	}
+
+	for week := 0; week < heatmapWeeks; week++ {
+		for weekday := 0; weekday < 7; weekday++ {
+			day := start.AddDate(0, 0, week*7+weekday)
+			if day.After(today0) {
+				break
+			}
+
+			if day.Day() == 1 {
+				drawText(img, left+week*step, top-4, muted, day.Format("Jan"))
+			}
+
+			level := 0
+			if c := counts[day.Format(time.DateOnly)]; c > 0 {
+				level = (c*(len(heatmapLevels)-1) + most - 1) / most
+			}
+
+			x, y := left+week*step, top+weekday*step
+			fillRect(img, image.Rect(x, y, x+cellSize, y+cellSize), heatmapLevels[level])
+		}
+	}
+
+	return img
+}
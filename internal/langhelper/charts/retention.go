@@ -0,0 +1,65 @@
+package charts
+
+import (
+	"fmt"
+	"image"
+)
+
+const (
+	curveWidth  = 480
+	curveHeight = 180
+	pointSize   = 5
+)
+
+// RetentionPoint is the share of reviews remembered after some time.
+type RetentionPoint struct {
+	Label     string
+	Retention float64
+	Reviews   int
+}
+
+// RetentionCurve draws a line through points, from 0% at the bottom to 100% at
+// the top. Points without reviews are skipped.
+func RetentionCurve(points []RetentionPoint) *image.RGBA {
+	var (
+		left   = padding + 4*charWidth + cellGap
+		top    = padding + 2*lineHeight
+		width  = left + curveWidth + padding
+		height = top + curveHeight + 2*lineHeight + padding
+		img    = newCanvas(width, height)
+		base   = top + curveHeight
+	)
+
+	drawText(img, padding, padding+lineHeight-3, foreground, "Retention by days since last review")
+	for p := 0; p <= 100; p += 25 {
+		y := base - p*curveHeight/100
+		drawLine(img, left, y, left+curveWidth, y, grid)
+		drawText(img, padding, y+lineHeight/2-2, muted, fmt.Sprintf("%d%%", p))
+	}
+
+	if len(points) == 0 {
+		return img
+	}
+
+	var (
+		step  = curveWidth / len(points)
+		prevX = -1
+		prevY = -1
+	)
+	for i, point := range points {
+		x := left + i*step + step/2
+		drawText(img, x-textWidth(point.Label)/2, base+lineHeight+2, muted, point.Label)
+		if point.Reviews == 0 {
+			continue
+		}
+
+		y := base - int(point.Retention*curveHeight)
+		if prevX >= 0 {
+			drawLine(img, prevX, prevY, x, y, foreground)
+		}
+		fillRect(img, image.Rect(x-pointSize/2, y-pointSize/2, x+pointSize/2+1, y+pointSize/2+1), accent)
+		prevX, prevY = x, y
+	}
+
+	return img
+}
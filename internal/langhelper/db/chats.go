@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DuplicatePolicy is what happens when a chat posts a word that already exists.
+type DuplicatePolicy string
+
+const (
+	// DuplicateReject keeps the existing word and tells the poster.
+	DuplicateReject DuplicatePolicy = "reject"
+	// DuplicateReplace overwrites the meaning, fields and photo of the word.
+	DuplicateReplace DuplicatePolicy = "replace"
+	// DuplicateAppend adds the meaning to the word as another numbered sense.
+	DuplicateAppend DuplicatePolicy = "append"
+	// DuplicateHomograph adds the word as a separate one, numbered like in a
+	// dictionary: bank, bank², bank³.
+	DuplicateHomograph DuplicatePolicy = "homograph"
+)
+
+var DuplicatePolicies = []DuplicatePolicy{DuplicateReject, DuplicateReplace, DuplicateAppend, DuplicateHomograph}
+
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	for _, policy := range DuplicatePolicies {
+		if string(policy) == s {
+			return policy, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid duplicate policy %q", s)
+}
+
+type (
+	// ChatsRepo keeps the settings of chats words are posted in, which may be
+	// channels and groups as well as private chats.
+	ChatsRepo struct {
+		db *sql.DB
+	}
+)
+
+func NewChatsRepo(db *sql.DB) (*ChatsRepo, error) {
+	repo := &ChatsRepo{db: db}
+	err := repo.init(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (repo *ChatsRepo) init(ctx context.Context) error {
+	_, err := repo.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS chats(
+    chat_id BIGINT PRIMARY KEY,
+    duplicates TEXT NOT NULL DEFAULT 'reject',
+    deck TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    shared BOOLEAN NOT NULL DEFAULT FALSE,
+    source_lang TEXT NOT NULL DEFAULT 'en',
+    target_lang TEXT NOT NULL DEFAULT ''
+)`)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range []struct{ name, definition string }{
+		{"deck", "TEXT NOT NULL DEFAULT ''"},
+		{"title", "TEXT NOT NULL DEFAULT ''"},
+		{"shared", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"source_lang", "TEXT NOT NULL DEFAULT 'en'"},
+		{"target_lang", "TEXT NOT NULL DEFAULT ''"},
+	} {
+		if _, err = addColumnIfNotExists(ctx, repo.db, "chats", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	// chats that posted words before they got a deck of their own keep
+	// posting to DefaultDeck, where their words are, instead of starting one
+	// named after them.
+	_, err = repo.db.ExecContext(ctx, `
+INSERT INTO chats (chat_id, deck) SELECT DISTINCT source_chat_id, $1 FROM words WHERE source_chat_id != 0
+ON CONFLICT (chat_id) DO UPDATE SET deck = excluded.deck WHERE chats.deck = ''`, DefaultDeck)
+	return err
+}
+
+// SetTitle records the title of a channel or group. A chat that has no deck
+// yet gets one named after it, which stays when the chat is renamed. The name
+// has the chat ID in it, as chats with the same title are not the same deck.
+func (repo *ChatsRepo) SetTitle(ctx context.Context, chatID int64, title string) error {
+	_, err := repo.db.ExecContext(ctx, `
+INSERT INTO chats (chat_id, title, deck) VALUES ($1, $2, $3)
+ON CONFLICT (chat_id) DO UPDATE SET title = excluded.title,
+    deck = CASE WHEN chats.deck = '' THEN excluded.deck ELSE chats.deck END`, chatID, title, chatDeck(chatID, title))
+	return err
+}
+
+// chatDeck names the deck of the chat with title.
+func chatDeck(chatID int64, title string) string {
+	return fmt.Sprintf("%s (%d)", title, chatID)
+}
+
+// GetDuplicatePolicy returns the duplicate policy of the chat, DuplicateReject
+// if it has never set one.
+func (repo *ChatsRepo) GetDuplicatePolicy(ctx context.Context, chatID int64) (DuplicatePolicy, error) {
+	var policy string
+	err := repo.db.QueryRowContext(ctx, "SELECT duplicates FROM chats WHERE chat_id = $1", chatID).Scan(&policy)
+	if err == sql.ErrNoRows {
+		return DuplicateReject, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return DuplicatePolicy(policy), nil
+}
+
+// GetDeck returns the deck words posted in the chat go to. That is the one
+// named after the chat, see SetTitle, unless it has set another, and
+// DefaultDeck for private chats.
+func (repo *ChatsRepo) GetDeck(ctx context.Context, chatID int64) (string, error) {
+	var deck string
+	err := repo.db.QueryRowContext(ctx, "SELECT deck FROM chats WHERE chat_id = $1", chatID).Scan(&deck)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	if deck == "" {
+		return DefaultDeck, nil
+	}
+
+	return deck, nil
+}
+
+// SetDeck names the deck new words posted in the chat go to.
+func (repo *ChatsRepo) SetDeck(ctx context.Context, chatID int64, deck string) error {
+	_, err := repo.db.ExecContext(ctx, `
+INSERT INTO chats (chat_id, deck) VALUES ($1, $2)
+ON CONFLICT (chat_id) DO UPDATE SET deck = excluded.deck`, chatID, deck)
+	return err
+}
+
+// GetShared reports whether words posted in a private chat are shared with
+// everyone instead of kept for the user, which is the default.
+func (repo *ChatsRepo) GetShared(ctx context.Context, chatID int64) (bool, error) {
+	var shared bool
+	err := repo.db.QueryRowContext(ctx, "SELECT shared FROM chats WHERE chat_id = $1", chatID).Scan(&shared)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+
+	return shared, nil
+}
+
+func (repo *ChatsRepo) SetShared(ctx context.Context, chatID int64, shared bool) error {
+	_, err := repo.db.ExecContext(ctx, `
+INSERT INTO chats (chat_id, shared) VALUES ($1, $2)
+ON CONFLICT (chat_id) DO UPDATE SET shared = excluded.shared`, chatID, shared)
+	return err
+}
+
+// GetLanguages returns the BCP-47 tags of the language words posted in the
+// chat are in and of the one their meanings are written in. The target is
+// empty when it was never set.
+func (repo *ChatsRepo) GetLanguages(ctx context.Context, chatID int64) (string, string, error) {
+	source, target := "en", ""
+	err := repo.db.QueryRowContext(ctx, "SELECT source_lang, target_lang FROM chats WHERE chat_id = $1", chatID).
+		Scan(&source, &target)
+	if err != nil && err != sql.ErrNoRows {
+		return "", "", err
+	}
+
+	return source, target, nil
+}
+
+func (repo *ChatsRepo) SetLanguages(ctx context.Context, chatID int64, source, target string) error {
+	_, err := repo.db.ExecContext(ctx, `
+INSERT INTO chats (chat_id, source_lang, target_lang) VALUES ($1, $2, $3)
+ON CONFLICT (chat_id) DO UPDATE SET source_lang = excluded.source_lang, target_lang = excluded.target_lang`,
+		chatID, source, target)
+	return err
+}
+
+func (repo *ChatsRepo) SetDuplicatePolicy(ctx context.Context, chatID int64, policy DuplicatePolicy) error {
+	_, err := repo.db.ExecContext(ctx, `
+INSERT INTO chats (chat_id, duplicates) VALUES ($1, $2)
+ON CONFLICT (chat_id) DO UPDATE SET duplicates = excluded.duplicates`, chatID, string(policy))
+	return err
+}
@@ -0,0 +1,304 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"strings"
+)
+
+// deckWordsSchema is the schema of deck_words with %s for the table name, so
+// the table can be rebuilt when a column needs a constraint. Decks only have
+// shared words, owner is there for the foreign key.
+const deckWordsSchema = `
+CREATE TABLE IF NOT EXISTS %s(
+    deck TEXT NOT NULL,
+    owner BIGINT NOT NULL DEFAULT 0,
+    word TEXT NOT NULL,
+    PRIMARY KEY(deck, word),
+    FOREIGN KEY(owner, word) REFERENCES words (owner, word)
+)`
+
+// sharedWord is the condition for deck_words rows whose word hasn't been
+// deleted.
+const sharedWord = `EXISTS (
+    SELECT 1 FROM words WHERE words.owner = deck_words.owner AND words.word = deck_words.word AND deleted_at IS NULL)`
+
+// DefaultDeck is the deck of words posted in private chats, and in channels
+// and groups that posted words before they got decks of their own. New users
+// are subscribed to it.
+const DefaultDeck = "main"
+
+type (
+	// DeckModel is a deck as a user sees it. Reverse is nil when the user's
+	// own reverse cards setting applies to it. Chats are the titles of the
+	// channels and groups posting to it.
+	DeckModel struct {
+		Name       string
+		Words      int
+		Subscribed bool
+		Reverse    *bool
+		Chats      []string
+		// SourceLang and TargetLang are the languages of the words of the
+		// deck and of their meanings, empty when they aren't all the same.
+		SourceLang string
+		TargetLang string
+	}
+
+	// DecksRepo keeps which decks every word is in and who is subscribed to
+	// them. A deck exists as long as it has words, so there is no table of
+	// decks.
+	DecksRepo struct {
+		db *sql.DB
+	}
+)
+
+func NewDecksRepo(db *sql.DB) (*DecksRepo, error) {
+	repo := &DecksRepo{db: db}
+	err := repo.init(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (repo *DecksRepo) init(ctx context.Context) error {
+	var exists bool
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'deck_words'").
+		Scan(&exists); err != nil {
+		return err
+	}
+
+	_, err := repo.db.ExecContext(ctx, fmt.Sprintf(deckWordsSchema, "deck_words")+`;
+CREATE TABLE IF NOT EXISTS subscriptions(
+    user_id BIGINT REFERENCES users (user_id),
+    deck TEXT NOT NULL,
+    reverse_cards BOOLEAN,
+    PRIMARY KEY(user_id, deck)
+)`)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		err = repo.migrate(ctx)
+	} else {
+		err = repo.migrateOwner(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS deck_words_word ON deck_words (word)")
+	return err
+}
+
+// migrate puts the words added before decks existed in DefaultDeck and the
+// decks of their tags, and subscribes everyone to all of them so nobody loses
+// a word.
+func (repo *DecksRepo) migrate(ctx context.Context) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		"INSERT INTO deck_words (deck, word) SELECT $1, word FROM words WHERE owner = 0",
+		"INSERT INTO deck_words (deck, word) SELECT value, word FROM word_fields WHERE kind = $1 AND owner = 0 ON CONFLICT DO NOTHING",
+		"INSERT INTO subscriptions (user_id, deck) SELECT user_id, deck FROM users, (SELECT DISTINCT deck FROM deck_words)",
+	}
+	args := [][]interface{}{{DefaultDeck}, {fieldTag}, nil}
+	for i, stmt := range stmts {
+		if _, err = tx.ExecContext(ctx, stmt, args[i]...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// migrateOwner adds owner to deck_words, which needs a rebuild for its foreign
+// key. It drops the index of the old table too.
+func (repo *DecksRepo) migrateOwner(ctx context.Context) error {
+	exists, err := columnExists(ctx, repo.db, "deck_words", "owner")
+	if err != nil || exists {
+		return err
+	}
+
+	return rebuildTable(ctx, repo.db, "deck_words", deckWordsSchema, nil)
+}
+
+// SetWordDecksTx puts word in decks and nothing else as part of tx.
+func (repo *DecksRepo) SetWordDecksTx(ctx context.Context, tx *sql.Tx, word string, decks []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM deck_words WHERE word = $1", word); err != nil {
+		return err
+	}
+
+	return repo.AddWordDecksTx(ctx, tx, word, decks)
+}
+
+// AddWordDecksTx adds word to decks as part of tx.
+func (repo *DecksRepo) AddWordDecksTx(ctx context.Context, tx *sql.Tx, word string, decks []string) error {
+	for _, deck := range decks {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO deck_words (deck, word) VALUES ($1, $2) ON CONFLICT DO NOTHING", deck, word); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SubscribersTx returns the users subscribed to any deck word is in as part
+// of tx.
+func (repo *DecksRepo) SubscribersTx(ctx context.Context, tx *sql.Tx, word string) ([]int64, error) {
+	return listIDs(tx.QueryContext(ctx, `
+SELECT DISTINCT user_id FROM subscriptions WHERE deck IN (SELECT deck FROM deck_words WHERE word = $1)`, word))
+}
+
+// List returns every deck with how many words it has and whether the user is
+// subscribed to it.
+func (repo *DecksRepo) List(ctx context.Context, userID int64) ([]DeckModel, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+SELECT decks.deck, COUNT(deck_words.word), subscriptions.user_id IS NOT NULL, subscriptions.reverse_cards,
+    COALESCE((SELECT GROUP_CONCAT(title, char(10)) FROM chats WHERE chats.deck = decks.deck AND title != ''), ''),
+    COALESCE((SELECT CASE WHEN COUNT(DISTINCT source_lang) = 1 THEN MAX(source_lang) ELSE '' END
+        FROM words JOIN deck_words languages ON languages.word = words.word
+        WHERE languages.deck = decks.deck AND owner = 0 AND deleted_at IS NULL), ''),
+    COALESCE((SELECT CASE WHEN COUNT(DISTINCT target_lang) = 1 THEN MAX(target_lang) ELSE '' END
+        FROM words JOIN deck_words languages ON languages.word = words.word
+        WHERE languages.deck = decks.deck AND owner = 0 AND deleted_at IS NULL), '')
+FROM (SELECT deck FROM deck_words UNION SELECT deck FROM subscriptions WHERE user_id = $1) decks
+LEFT JOIN deck_words ON deck_words.deck = decks.deck
+    AND `+sharedWord+`
+LEFT JOIN subscriptions ON subscriptions.deck = decks.deck AND subscriptions.user_id = $1
+GROUP BY decks.deck ORDER BY decks.deck`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []DeckModel
+	for rows.Next() {
+		var (
+			res     DeckModel
+			reverse sql.NullBool
+			chats   string
+		)
+		if err = rows.Scan(&res.Name, &res.Words, &res.Subscribed, &reverse, &chats,
+			&res.SourceLang, &res.TargetLang); err != nil {
+			return nil, err
+		}
+		if reverse.Valid {
+			res.Reverse = &reverse.Bool
+		}
+		if chats != "" {
+			res.Chats = strings.Split(chats, "\n")
+		}
+		list = append(list, res)
+	}
+
+	return list, rows.Err()
+}
+
+// Subscribe subscribes the user to deck and returns its words, which the user
+// needs cards for.
+func (repo *DecksRepo) Subscribe(ctx context.Context, userID int64, deck string) ([]WordsModel, error) {
+	if _, err := repo.db.ExecContext(ctx, "INSERT INTO subscriptions (user_id, deck) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		userID, deck); err != nil {
+		return nil, err
+	}
+
+	return repo.words(ctx, "SELECT owner, word FROM deck_words WHERE deck = $1 AND "+sharedWord, deck)
+}
+
+// SubscribeDefault subscribes a user who isn't subscribed to anything, like a
+// new one, to DefaultDeck. It returns the words the user should have cards
+// for: the ones of every deck they are subscribed to and their own.
+func (repo *DecksRepo) SubscribeDefault(ctx context.Context, userID int64) ([]WordsModel, error) {
+	if _, err := repo.db.ExecContext(ctx, `
+INSERT INTO subscriptions (user_id, deck) SELECT $1, $2
+WHERE NOT EXISTS (SELECT 1 FROM subscriptions WHERE user_id = $1)`, userID, DefaultDeck); err != nil {
+		return nil, err
+	}
+
+	return repo.words(ctx, `
+SELECT owner, word FROM deck_words WHERE deck IN (SELECT deck FROM subscriptions WHERE user_id = $1) AND `+sharedWord+`
+UNION SELECT owner, word FROM words WHERE owner = $1 AND deleted_at IS NULL`, userID)
+}
+
+// Unsubscribe unsubscribes the user from deck. The cards of its words are
+// kept, but only asked while another subscribed deck has them. It returns
+// sql.ErrNoRows if the user wasn't subscribed.
+func (repo *DecksRepo) Unsubscribe(ctx context.Context, userID int64, deck string) error {
+	res, err := repo.db.ExecContext(ctx, "DELETE FROM subscriptions WHERE user_id = $1 AND deck = $2", userID, deck)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetReverseCards turns reverse cards of the words in deck on or off for the
+// user, or back to the user's own setting if enabled is nil. It returns
+// sql.ErrNoRows if the user isn't subscribed to deck.
+func (repo *DecksRepo) SetReverseCards(ctx context.Context, userID int64, deck string, enabled *bool) error {
+	res, err := repo.db.ExecContext(ctx, "UPDATE subscriptions SET reverse_cards = $1 WHERE user_id = $2 AND deck = $3",
+		enabled, userID, deck)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (repo *DecksRepo) words(ctx context.Context, query string, args ...interface{}) ([]WordsModel, error) {
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []WordsModel
+	for rows.Next() {
+		var res WordsModel
+		if err = rows.Scan(&res.Owner, &res.Word); err != nil {
+			return nil, err
+		}
+		list = append(list, res)
+	}
+
+	return list, rows.Err()
+}
+
+func listIDs(rows *sql.Rows, err error) ([]int64, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		list = append(list, id)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	fsrsDecay            = -0.5
+	fsrsFactor           = 19.0 / 81.0
+	fsrsRequestRetention = 0.9
+	fsrsMaxInterval      = 36500
+)
+
+// fsrsWeights are the default FSRS-4.5 parameters.
+var fsrsWeights = [17]float64{
+	0.4, 0.6, 2.4, 5.8, 4.93, 0.94, 0.86, 0.01, 1.49, 0.14, 0.94, 2.18, 0.05, 0.34, 1.26, 0.29, 2.61,
+}
+
+// FSRSScheduler implements the Free Spaced Repetition Scheduler (FSRS-4.5). A
+// word without stability has never been reviewed by it.
+type FSRSScheduler struct{}
+
+func (FSRSScheduler) Name() string { return "fsrs" }
+
+func (FSRSScheduler) Description() string {
+	return "FSRS, models how likely you are to remember a word"
+}
+
+func (FSRSScheduler) DueColumn() string { return "fsrs_due_at" }
+
+func (FSRSScheduler) NextReview(userWord *UserWordModel) time.Time { return userWord.FSRSDueAt }
+
+func (FSRSScheduler) Review(userWord *UserWordModel, grade Grade, now time.Time) {
+	w := fsrsWeights
+	g := float64(grade + 1) // FSRS rates from 1 (again) to 4 (easy)
+
+	if userWord.FSRSStability <= 0 {
+		userWord.FSRSStability = w[int(g)-1]
+		userWord.FSRSDifficulty = fsrsInitialDifficulty(g)
+	} else {
+		var elapsed float64
+		if !userWord.LastReviewed.IsZero() {
+			elapsed = math.Max(0, now.Sub(userWord.LastReviewed).Hours()/24)
+		}
+		s, d := userWord.FSRSStability, userWord.FSRSDifficulty
+		r := math.Pow(1+fsrsFactor*elapsed/s, fsrsDecay)
+
+		if grade == GradeAgain {
+			userWord.FSRSStability = w[11] * math.Pow(d, -w[12]) * (math.Pow(s+1, w[13]) - 1) * math.Exp(w[14]*(1-r))
+		} else {
+			modifier := 1.0
+			if grade == GradeHard {
+				modifier = w[15]
+			} else if grade == GradeEasy {
+				modifier = w[16]
+			}
+			userWord.FSRSStability = s * (1 + math.Exp(w[8])*(11-d)*math.Pow(s, -w[9])*(math.Exp(w[10]*(1-r))-1)*modifier)
+		}
+
+		d -= w[6] * (g - 3)
+		userWord.FSRSDifficulty = fsrsClampDifficulty(w[7]*fsrsInitialDifficulty(3) + (1-w[7])*d)
+	}
+
+	interval := userWord.FSRSStability / fsrsFactor * (math.Pow(fsrsRequestRetention, 1/fsrsDecay) - 1)
+	days := int(math.Min(math.Max(math.Round(interval), 1), fsrsMaxInterval))
+	userWord.FSRSDueAt = now.AddDate(0, 0, days)
+}
+
+func fsrsInitialDifficulty(g float64) float64 {
+	return fsrsClampDifficulty(fsrsWeights[4] - (g-3)*fsrsWeights[5])
+}
+
+func fsrsClampDifficulty(d float64) float64 {
+	return math.Min(math.Max(d, 1), 10)
+}
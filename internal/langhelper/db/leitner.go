@@ -0,0 +1,41 @@
+package db
+
+import "time"
+
+// leitnerIntervals is how many days a word waits in each box.
+var leitnerIntervals = []int{1, 2, 4, 8, 16, 32}
+
+// LeitnerScheduler moves a word one box up when it is remembered and back to
+// the first box when it is forgotten. Hard keeps the word in its box.
+type LeitnerScheduler struct{}
+
+func (LeitnerScheduler) Name() string { return "leitner" }
+
+func (LeitnerScheduler) Description() string {
+	return "Leitner boxes, a forgotten word goes back to box 1"
+}
+
+func (LeitnerScheduler) DueColumn() string { return "leitner_due_at" }
+
+func (LeitnerScheduler) NextReview(userWord *UserWordModel) time.Time { return userWord.LeitnerDueAt }
+
+func (LeitnerScheduler) Review(userWord *UserWordModel, grade Grade, now time.Time) {
+	switch grade {
+	case GradeAgain:
+		userWord.LeitnerBox = 1
+	case GradeHard:
+	case GradeGood:
+		userWord.LeitnerBox++
+	case GradeEasy:
+		userWord.LeitnerBox += 2
+	}
+
+	if userWord.LeitnerBox < 1 {
+		userWord.LeitnerBox = 1
+	}
+	if userWord.LeitnerBox > len(leitnerIntervals) {
+		userWord.LeitnerBox = len(leitnerIntervals)
+	}
+
+	userWord.LeitnerDueAt = now.AddDate(0, 0, leitnerIntervals[userWord.LeitnerBox-1])
+}
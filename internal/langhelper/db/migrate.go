@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func columnExists(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info($1) WHERE name = $2", table, column).
+		Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// addColumnIfNotExists adds column to table when it is missing. sqlite has no
+// ADD COLUMN IF NOT EXISTS, so we have to ask pragma_table_info first.
+func addColumnIfNotExists(ctx context.Context, db *sql.DB, table, column, definition string) (bool, error) {
+	exists, err := columnExists(ctx, db, table, column)
+	if err != nil || exists {
+		return false, err
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// rebuildTable recreates table from schema, which is a CREATE TABLE statement
+// with a %s in place of the table name, and copies every row over. This is the
+// only way to change things like the primary key in sqlite. after, if not nil,
+// runs in the same transaction once the new table is in place.
+func rebuildTable(ctx context.Context, db *sql.DB, table, schema string, after func(tx *sql.Tx) error) error {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info($1)", table)
+	if err != nil {
+		return err
+	}
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, column)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tmp := table + "_new"
+	stmts := []string{
+		fmt.Sprintf(schema, tmp),
+		fmt.Sprintf("INSERT INTO %s (%[2]s) SELECT %[2]s FROM %s", tmp, strings.Join(columns, ", "), table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmp, table),
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if after != nil {
+		if err = after(tx); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"time"
+)
+
+// reviewsSchema is the schema of reviews with %s for the table name, so the
+// table can be rebuilt when a column needs a constraint.
+const reviewsSchema = `
+CREATE TABLE IF NOT EXISTS %s(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT REFERENCES users (user_id),
+    owner BIGINT NOT NULL DEFAULT 0,
+    word TEXT,
+    reverse BOOLEAN NOT NULL DEFAULT FALSE,
+    sense INTEGER NOT NULL DEFAULT 0,
+    grade INTEGER NOT NULL,
+    latency_ms INTEGER,
+    reviewed_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(owner, word) REFERENCES words (owner, word)
+)`
+
+type (
+	// ReviewModel is a single answer of a user to a card. Latency is how long it
+	// took them to answer, zero if unknown. Owner and Sense are the ones of the
+	// card, see UserWordModel.
+	ReviewModel struct {
+		UserID     int64
+		Owner      int64
+		Word       string
+		Reverse    bool
+		Sense      int
+		Grade      Grade
+		Latency    time.Duration
+		ReviewedAt time.Time
+	}
+
+	ReviewStatsModel struct {
+		Total      int
+		Correct    int
+		AvgLatency time.Duration
+	}
+
+	FailedWordModel struct {
+		Word     string
+		Failures int
+	}
+
+	ReviewsRepo struct {
+		db *sql.DB
+	}
+)
+
+func NewReviewsRepo(db *sql.DB) (*ReviewsRepo, error) {
+	repo := &ReviewsRepo{db: db}
+	err := repo.init(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (repo *ReviewsRepo) init(ctx context.Context) error {
+	if _, err := repo.db.ExecContext(ctx, fmt.Sprintf(reviewsSchema, "reviews")); err != nil {
+		return err
+	}
+
+	// reviews from before senses had their own cards were of a card that asks
+	// all of them.
+	if _, err := addColumnIfNotExists(ctx, repo.db, "reviews", "sense", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// reviews from before private words existed are all of shared ones. The
+	// foreign key of owner needs a rebuild, which drops the index too.
+	exists, err := columnExists(ctx, repo.db, "reviews", "owner")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err = rebuildTable(ctx, repo.db, "reviews", reviewsSchema, nil); err != nil {
+			return err
+		}
+	}
+
+	_, err = repo.db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS reviews_user_id_reviewed_at ON reviews (user_id, reviewed_at)")
+	return err
+}
+
+func (repo *ReviewsRepo) Insert(ctx context.Context, model ReviewModel) error {
+	var latency sql.NullInt64
+	if model.Latency > 0 {
+		latency = sql.NullInt64{Int64: model.Latency.Milliseconds(), Valid: true}
+	}
+
+	_, err := repo.db.ExecContext(ctx,
+		"INSERT INTO reviews (user_id, owner, word, reverse, sense, grade, latency_ms, reviewed_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		model.UserID, model.Owner, model.Word, model.Reverse, model.Sense, model.Grade, latency, model.ReviewedAt)
+	return err
+}
+
+func (repo *ReviewsRepo) GetStats(ctx context.Context, userID int64) (*ReviewStatsModel, error) {
+	var (
+		res     ReviewStatsModel
+		latency sql.NullFloat64
+	)
+	if err := repo.db.QueryRowContext(ctx, `
+SELECT COUNT(*), COUNT(*) FILTER (WHERE grade != $1), AVG(latency_ms)
+FROM reviews WHERE user_id = $2`, GradeAgain, userID).
+		Scan(&res.Total, &res.Correct, &latency); err != nil {
+		return nil, err
+	}
+	res.AvgLatency = time.Duration(latency.Float64 * float64(time.Millisecond))
+
+	return &res, nil
+}
+
+// CountSince returns how many reviews the user has done since since.
+func (repo *ReviewsRepo) CountSince(ctx context.Context, userID int64, since time.Time) (int, error) {
+	var count int
+	err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM reviews WHERE user_id = $1 AND reviewed_at >= $2",
+		userID, since.In(time.UTC)).Scan(&count)
+
+	return count, err
+}
+
+// CountQueuesSince returns how many new cards the user has seen for the first
+// time since since, and how many reviews of other cards they have done.
+func (repo *ReviewsRepo) CountQueuesSince(ctx context.Context, userID int64, since time.Time) (int, int, error) {
+	var newCards, reviews int
+	err := repo.db.QueryRowContext(ctx, `
+SELECT
+    COUNT(*) FILTER (WHERE NOT EXISTS (
+        SELECT 1 FROM reviews p
+        WHERE p.user_id = r.user_id AND p.word = r.word AND p.reverse = r.reverse AND p.sense = r.sense
+            AND p.reviewed_at < r.reviewed_at
+    )),
+    COUNT(*)
+FROM reviews r WHERE r.user_id = $1 AND r.reviewed_at >= $2`, userID, since.In(time.UTC)).Scan(&newCards, &reviews)
+
+	return newCards, reviews - newCards, err
+}
+
+// Retention returns the share of reviews since since that were remembered,
+// leaving out the first time each card was seen, and how many reviews that is.
+func (repo *ReviewsRepo) Retention(ctx context.Context, userID int64, since time.Time) (float64, int, error) {
+	var (
+		total   int
+		correct int
+	)
+	if err := repo.db.QueryRowContext(ctx, `
+SELECT COUNT(*), COUNT(*) FILTER (WHERE grade != $1) FROM reviews r
+WHERE r.user_id = $2 AND r.reviewed_at >= $3 AND EXISTS (
+    SELECT 1 FROM reviews p
+    WHERE p.user_id = r.user_id AND p.word = r.word AND p.reverse = r.reverse AND p.sense = r.sense
+        AND p.reviewed_at < r.reviewed_at
+)`, GradeAgain, userID, since.In(time.UTC)).
+		Scan(&total, &correct); err != nil {
+		return 0, 0, err
+	}
+
+	if total == 0 {
+		return 0, 0, nil
+	}
+
+	return float64(correct) / float64(total), total, nil
+}
+
+// MostFailed returns up to n words the user has answered "again" the most.
+func (repo *ReviewsRepo) MostFailed(ctx context.Context, userID int64, n int) ([]FailedWordModel, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+SELECT word, COUNT(*) AS failures FROM reviews
+WHERE user_id = $1 AND grade = $2
+GROUP BY word ORDER BY failures DESC, MAX(reviewed_at) DESC LIMIT $3`, userID, GradeAgain, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []FailedWordModel
+	for rows.Next() {
+		var res FailedWordModel
+		if err = rows.Scan(&res.Word, &res.Failures); err != nil {
+			return nil, err
+		}
+		list = append(list, res)
+	}
+
+	return list, rows.Err()
+}
+
+// List returns every review of the user, oldest first.
+func (repo *ReviewsRepo) List(ctx context.Context, userID int64) ([]ReviewModel, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+SELECT user_id, word, reverse, sense, grade, latency_ms, reviewed_at FROM reviews
+WHERE user_id = $1 ORDER BY reviewed_at ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ReviewModel
+	for rows.Next() {
+		var (
+			res     ReviewModel
+			latency sql.NullInt64
+		)
+		if err = rows.Scan(&res.UserID, &res.Word, &res.Reverse, &res.Sense, &res.Grade, &latency, &res.ReviewedAt); err != nil {
+			return nil, err
+		}
+		res.Latency = time.Duration(latency.Int64) * time.Millisecond
+		list = append(list, res)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Grade is how well the user remembered a word.
+type Grade int
+
+const (
+	GradeAgain Grade = iota
+	GradeHard
+	GradeGood
+	GradeEasy
+)
+
+var gradeNames = map[Grade]string{
+	GradeAgain: "again",
+	GradeHard:  "hard",
+	GradeGood:  "good",
+	GradeEasy:  "easy",
+}
+
+func (g Grade) String() string {
+	return gradeNames[g]
+}
+
+func ParseGrade(s string) (Grade, error) {
+	for grade, name := range gradeNames {
+		if name == s {
+			return grade, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid grade %q", s)
+}
+
+// Scheduler decides which word is asked next and when a graded word is due again.
+//
+// Every scheduler keeps its own state in user_words and all of them are updated
+// on each review, so users can switch between them without losing history.
+type Scheduler interface {
+	// Name is what users pass to /scheduler.
+	Name() string
+	// Description is shown next to Name when listing schedulers.
+	Description() string
+	// DueColumn is the user_words column the next word is picked by, earliest first.
+	DueColumn() string
+	// Review updates the state this scheduler keeps on userWord after it was graded at now.
+	Review(userWord *UserWordModel, grade Grade, now time.Time)
+	// NextReview is when userWord is due according to this scheduler.
+	NextReview(userWord *UserWordModel) time.Time
+}
+
+const DefaultScheduler = "sm2"
+
+var Schedulers = []Scheduler{
+	SM2Scheduler{},
+	LeitnerScheduler{},
+	FSRSScheduler{},
+	OldestScheduler{},
+}
+
+// GetScheduler returns the scheduler called name, falling back to the default.
+func GetScheduler(name string) Scheduler {
+	if s, ok := LookupScheduler(name); ok {
+		return s
+	}
+
+	s, _ := LookupScheduler(DefaultScheduler)
+	return s
+}
+
+func LookupScheduler(name string) (Scheduler, bool) {
+	for _, s := range Schedulers {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+// ReviewAll applies grade to userWord with every scheduler and counts a lapse
+// if a card that had been reviewed before is forgotten.
+func ReviewAll(userWord *UserWordModel, grade Grade, now time.Time) {
+	if grade == GradeAgain && !userWord.IsNew() {
+		userWord.Lapses++
+	}
+
+	for _, s := range Schedulers {
+		s.Review(userWord, grade, now)
+	}
+	userWord.LastReviewed = now
+}
+
+// KnownInterval is how many days a word marked as known waits before it is
+// asked again.
+const KnownInterval = 180
+
+// MarkKnown schedules userWord KnownInterval days ahead with every scheduler,
+// as if it had been remembered for a long time.
+func MarkKnown(userWord *UserWordModel, now time.Time) {
+	due := now.AddDate(0, 0, KnownInterval)
+
+	userWord.Interval = KnownInterval
+	userWord.Repetitions = max(userWord.Repetitions, 2)
+	userWord.DueAt = due
+
+	userWord.LeitnerBox = len(leitnerIntervals)
+	userWord.LeitnerDueAt = due
+
+	// at the requested retention the FSRS interval equals the stability.
+	userWord.FSRSStability = KnownInterval
+	if userWord.FSRSDifficulty <= 0 {
+		userWord.FSRSDifficulty = fsrsInitialDifficulty(float64(GradeEasy + 1))
+	}
+	userWord.FSRSDueAt = due
+
+	userWord.LastReviewed = now
+}
+
+// OldestScheduler is the original behaviour of the bot: ask the word that has
+// not been asked for the longest time, regardless of grades.
+type OldestScheduler struct{}
+
+func (OldestScheduler) Name() string { return "oldest" }
+
+func (OldestScheduler) Description() string { return "the word you haven't seen the longest" }
+
+func (OldestScheduler) DueColumn() string { return "last_asked" }
+
+func (OldestScheduler) Review(*UserWordModel, Grade, time.Time) {}
+
+func (OldestScheduler) NextReview(userWord *UserWordModel) time.Time { return userWord.LastAsked }
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// senseNumber matches the number in front of each sense of a meaning with more
+// than one.
+var senseNumber = regexp.MustCompile(`(?m)^\d+\.\s+`)
+
+// sensesSchema is the schema of senses, with %s for the table name so it can
+// be rebuilt.
+const sensesSchema = `
+CREATE TABLE IF NOT EXISTS %s(
+    owner BIGINT NOT NULL DEFAULT 0,
+    word TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    definition TEXT NOT NULL,
+    pos TEXT NOT NULL DEFAULT '',
+    example TEXT NOT NULL DEFAULT '',
+    file_id TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY(owner, word, position),
+    FOREIGN KEY(owner, word) REFERENCES words (owner, word)
+)`
+
+// SenseModel is one meaning of a word. Position starts at 1. A sense without a
+// FileID uses the photo of its word.
+type SenseModel struct {
+	Position     int
+	Definition   string
+	PartOfSpeech string
+	Example      string
+	FileID       string
+}
+
+// SplitMeaning splits a meaning numbered like "1. ...\n2. ..." into its
+// senses. Any other meaning is a single sense.
+func SplitMeaning(meaning string) []string {
+	meaning = strings.TrimSpace(meaning)
+	if loc := senseNumber.FindStringIndex(meaning); loc == nil || loc[0] != 0 {
+		return []string{meaning}
+	}
+
+	var senses []string
+	for _, sense := range senseNumber.Split(meaning, -1) {
+		if sense = strings.TrimSpace(sense); sense != "" {
+			senses = append(senses, sense)
+		}
+	}
+
+	return senses
+}
+
+// JoinSenses is the meaning of a word with senses, numbered if there is more
+// than one.
+func JoinSenses(senses []SenseModel) string {
+	if len(senses) == 1 {
+		return senses[0].Definition
+	}
+
+	lines := make([]string, 0, len(senses))
+	for i, sense := range senses {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, sense.Definition))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// sensesOf returns the senses of model, made from its meaning if it has none.
+// A single sense gets the part of speech and the first example of the word.
+func sensesOf(model WordsModel) []SenseModel {
+	if len(model.Senses) > 0 {
+		return model.Senses
+	}
+
+	definitions := SplitMeaning(model.Meaning)
+	senses := make([]SenseModel, 0, len(definitions))
+	for _, definition := range definitions {
+		senses = append(senses, SenseModel{Definition: definition})
+	}
+	if len(senses) == 1 {
+		senses[0].PartOfSpeech = model.PartOfSpeech
+		if len(model.Examples) > 0 {
+			senses[0].Example = model.Examples[0]
+		}
+	}
+
+	return senses
+}
+
+// migrateSenses splits the meaning of words added before senses existed. Their
+// cards keep asking the whole word.
+func (repo *WordsRepo) migrateSenses(ctx context.Context) error {
+	rows, err := repo.db.QueryContext(ctx, `
+SELECT owner, word, COALESCE(meaning, ''), pos FROM words
+WHERE NOT EXISTS (SELECT 1 FROM senses WHERE senses.owner = words.owner AND senses.word = words.word)`)
+	if err != nil {
+		return err
+	}
+
+	var models []WordsModel
+	for rows.Next() {
+		var model WordsModel
+		if err = rows.Scan(&model.Owner, &model.Word, &model.Meaning, &model.PartOfSpeech); err != nil {
+			rows.Close()
+			return err
+		}
+		models = append(models, model)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil || len(models) == 0 {
+		return err
+	}
+
+	tx, err := repo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, model := range models {
+		if err = repo.setSenses(ctx, tx, model.Word, model); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// setSenses replaces the senses of word of model.Owner with the ones of model,
+// which may have a new name for it. A sense without a photo keeps the one the
+// sense in its place had.
+func (repo *WordsRepo) setSenses(ctx context.Context, tx *sql.Tx, word string, model WordsModel) error {
+	old, err := getSenses(ctx, tx, model.Owner, word)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM senses WHERE owner = $1 AND word = $2", model.Owner, word); err != nil {
+		return err
+	}
+
+	senses := append([]SenseModel(nil), sensesOf(model)...)
+	for i := range senses {
+		if senses[i].FileID == "" && i < len(old) {
+			senses[i].FileID = old[i].FileID
+		}
+	}
+
+	return repo.insertSenses(ctx, tx, model.Owner, model.Word, senses, 1)
+}
+
+// insertSenses adds senses to word of owner numbering them from first.
+func (repo *WordsRepo) insertSenses(ctx context.Context, tx *sql.Tx, owner int64, word string, senses []SenseModel, first int) error {
+	for i, sense := range senses {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO senses (owner, word, position, definition, pos, example, file_id) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			owner, word, first+i, sense.Definition, sense.PartOfSpeech, sense.Example, sense.FileID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getSenses returns the senses of word of owner in order.
+func getSenses(ctx context.Context, q interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}, owner int64, word string) ([]SenseModel, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT position, definition, pos, example, file_id FROM senses WHERE owner = $1 AND word = $2 ORDER BY position", owner, word)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []SenseModel
+	for rows.Next() {
+		var sense SenseModel
+		if err = rows.Scan(&sense.Position, &sense.Definition, &sense.PartOfSpeech, &sense.Example, &sense.FileID); err != nil {
+			return nil, err
+		}
+		list = append(list, sense)
+	}
+
+	return list, rows.Err()
+}
+
+// Sense returns sense n of the word, nil if it has no such sense. Sense 0 is
+// the whole word.
+func (model *WordsModel) Sense(n int) *SenseModel {
+	if n < 1 || n > len(model.Senses) {
+		return nil
+	}
+
+	return &model.Senses[n-1]
+}
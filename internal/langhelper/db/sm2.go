@@ -0,0 +1,61 @@
+package db
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	defaultEaseFactor = 2.5
+	minEaseFactor     = 1.3
+)
+
+// SM2Scheduler implements the SuperMemo 2 algorithm.
+type SM2Scheduler struct{}
+
+func (SM2Scheduler) Name() string { return "sm2" }
+
+func (SM2Scheduler) Description() string { return "SuperMemo 2, intervals grow with an ease factor" }
+
+func (SM2Scheduler) DueColumn() string { return "due_at" }
+
+func (SM2Scheduler) NextReview(userWord *UserWordModel) time.Time { return userWord.DueAt }
+
+func (SM2Scheduler) Review(userWord *UserWordModel, grade Grade, now time.Time) {
+	q := sm2Quality(grade)
+	if q < 3 {
+		userWord.Repetitions = 0
+		userWord.Interval = 1
+	} else {
+		switch userWord.Repetitions {
+		case 0:
+			userWord.Interval = 1
+		case 1:
+			userWord.Interval = 6
+		default:
+			userWord.Interval = int(math.Round(float64(userWord.Interval) * userWord.EaseFactor))
+		}
+		userWord.Repetitions++
+	}
+
+	userWord.EaseFactor += 0.1 - (5-q)*(0.08+(5-q)*0.02)
+	if userWord.EaseFactor < minEaseFactor {
+		userWord.EaseFactor = minEaseFactor
+	}
+
+	userWord.DueAt = now.AddDate(0, 0, userWord.Interval)
+}
+
+// sm2Quality maps a grade to the 0-5 response quality scale of SM-2.
+func sm2Quality(g Grade) float64 {
+	switch g {
+	case GradeAgain:
+		return 1
+	case GradeHard:
+		return 3
+	case GradeGood:
+		return 4
+	default:
+		return 5
+	}
+}
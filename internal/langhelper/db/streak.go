@@ -0,0 +1,73 @@
+package db
+
+import "time"
+
+const maxStreakFreezes = 2
+
+// StreakMilestones are the streak lengths worth celebrating.
+var StreakMilestones = []int{7, 30, 50, 100, 200, 365, 500, 1000}
+
+// StreakModel is the daily goal of a user and how many days in a row they have
+// met it. Days are time.DateOnly dates in the user's timezone.
+type StreakModel struct {
+	UserID    int64
+	DailyGoal int
+	Streak    int
+	Freezes   int
+	LastDay   string
+}
+
+// Current returns the streak as of today. A streak survives missed days as
+// long as there are enough freezes to cover them.
+func (s *StreakModel) Current(today string) int {
+	if s.LastDay == "" {
+		return 0
+	}
+
+	if missed := daysBetween(s.LastDay, today) - 1; missed > s.Freezes {
+		return 0
+	}
+
+	return s.Streak
+}
+
+// Meet records that the goal was met today. It uses up freezes for missed
+// days, earns a freeze every 7 days and reports whether the new streak is a
+// milestone. Meeting the goal twice on the same day does nothing.
+func (s *StreakModel) Meet(today string) bool {
+	if s.LastDay == today {
+		return false
+	}
+
+	if s.LastDay == "" {
+		s.Streak = 1
+	} else if missed := daysBetween(s.LastDay, today) - 1; missed <= s.Freezes {
+		s.Freezes -= missed
+		s.Streak++
+	} else {
+		s.Streak = 1
+	}
+	s.LastDay = today
+
+	if s.Streak%7 == 0 && s.Freezes < maxStreakFreezes {
+		s.Freezes++
+	}
+
+	for _, m := range StreakMilestones {
+		if s.Streak == m {
+			return true
+		}
+	}
+
+	return false
+}
+
+func daysBetween(from, to string) int {
+	f, err1 := time.Parse(time.DateOnly, from)
+	t, err2 := time.Parse(time.DateOnly, to)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+
+	return int(t.Sub(f).Hours() / 24)
+}
@@ -0,0 +1,12 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// execer is what *sql.DB and *sql.Tx have in common, so the same statements
+// can run on their own or as part of a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
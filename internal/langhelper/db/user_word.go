@@ -9,11 +9,104 @@ import (
 	"time"
 )
 
+const (
+	userWordColumns = "user_id, owner, word, reverse, sense, last_asked, last_reviewed, ease_factor, interval_days, repetitions, due_at, " +
+		"leitner_box, leitner_due_at, fsrs_stability, fsrs_difficulty, fsrs_due_at, lapses, leech"
+
+	// subscribed is the condition for cards whose word is in a deck the user
+	// is subscribed to or is their own.
+	subscribed = `(owner = user_words.user_id OR EXISTS (
+    SELECT 1 FROM deck_words JOIN subscriptions ON subscriptions.deck = deck_words.deck
+    WHERE subscriptions.user_id = user_words.user_id
+        AND deck_words.owner = user_words.owner AND deck_words.word = user_words.word))`
+
+	// inRotation is the condition for cards that are asked in normal reviews.
+	inRotation = "NOT suspended AND NOT leech AND " + visible + " AND " + subscribed
+
+	// reverseEnabled is whether the user wants reverse cards of the word. The
+	// setting of a deck the word is in wins over the user's own.
+	reverseEnabled = `COALESCE(
+    (SELECT MAX(subscriptions.reverse_cards) FROM subscriptions JOIN deck_words ON deck_words.deck = subscriptions.deck
+     WHERE subscriptions.user_id = user_words.user_id
+         AND deck_words.owner = user_words.owner AND deck_words.word = user_words.word),
+    (SELECT reverse_cards FROM users WHERE users.user_id = user_words.user_id))`
+
+	userWordsSchema = `
+CREATE TABLE IF NOT EXISTS %s(
+    user_id BIGINT REFERENCES users (user_id),
+    owner BIGINT NOT NULL DEFAULT 0,
+    word TEXT NOT NULL,
+    reverse BOOLEAN NOT NULL DEFAULT FALSE,
+    sense INTEGER NOT NULL DEFAULT 0,
+    last_asked TIMESTAMP,
+    last_reviewed TIMESTAMP,
+    ease_factor REAL NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    due_at TIMESTAMP,
+    leitner_box INTEGER NOT NULL DEFAULT 1,
+    leitner_due_at TIMESTAMP,
+    fsrs_stability REAL NOT NULL DEFAULT 0,
+    fsrs_difficulty REAL NOT NULL DEFAULT 0,
+    fsrs_due_at TIMESTAMP,
+    suspended BOOLEAN NOT NULL DEFAULT FALSE,
+    buried_until TIMESTAMP,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    leech BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY(user_id, word, reverse, sense),
+    FOREIGN KEY(owner, word) REFERENCES words (owner, word)
+)`
+)
+
+// Queue is a group of cards asked with its own daily limit.
+type Queue int
+
+const (
+	QueueReview Queue = iota
+	QueueNew
+)
+
 type (
 	UserWordModel struct {
-		UserID    int64
-		Word      string
-		LastAsked time.Time
+		UserID int64
+		// Owner is the owner of the word, see WordsModel. A user has one set of
+		// cards for each word name, of their own word if they have one.
+		Owner int64
+		Word  string
+		// Reverse cards show the meaning and ask for the word.
+		Reverse bool
+		// Sense is the sense of the word the card asks, 0 for all of them.
+		Sense        int
+		LastAsked    time.Time
+		LastReviewed time.Time
+
+		// SM-2 scheduling state
+		EaseFactor  float64
+		Interval    int // in days
+		Repetitions int
+		DueAt       time.Time
+
+		// Leitner scheduling state
+		LeitnerBox   int
+		LeitnerDueAt time.Time
+
+		// FSRS scheduling state
+		FSRSStability  float64
+		FSRSDifficulty float64
+		FSRSDueAt      time.Time
+
+		// Lapses is how many times the card was forgotten after it had been
+		// learned. A Leech has lapsed too often and is only asked in /leeches.
+		Lapses int
+		Leech  bool
+	}
+
+	// SuspendedWordModel is a word the user has taken out of rotation, for
+	// good or until BuriedUntil.
+	SuspendedWordModel struct {
+		Word        string
+		Suspended   bool
+		BuriedUntil time.Time
 	}
 
 	UserWordsRepo struct {
@@ -32,62 +125,281 @@ func NewUserWordRepo(db *sql.DB) (*UserWordsRepo, error) {
 }
 
 func (repo *UserWordsRepo) init(ctx context.Context) error {
-	_, err := repo.db.ExecContext(ctx, `
-CREATE TABLE IF NOT EXISTS user_words(
-    user_id BIGINT REFERENCES users (user_id),
-    word TEXT REFERENCES words (word),
-    last_asked TIMESTAMP,
-    PRIMARY KEY(user_id, word)
-)`)
+	var exists bool
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'user_words'").
+		Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return repo.migrate(ctx)
+	}
 
+	_, err := repo.db.ExecContext(ctx, fmt.Sprintf(userWordsSchema, "user_words"))
 	return err
 }
 
+// migrate brings user_words tables created before spaced repetition up to date.
+// Existing rows keep their order: a card is due when it was last asked, so the
+// ones nobody has seen for the longest time come first. Every scheduler starts
+// from the same due date.
+func (repo *UserWordsRepo) migrate(ctx context.Context) error {
+	columns := []struct{ name, definition string }{
+		{"ease_factor", "REAL NOT NULL DEFAULT 2.5"},
+		{"interval_days", "INTEGER NOT NULL DEFAULT 0"},
+		{"repetitions", "INTEGER NOT NULL DEFAULT 0"},
+		{"due_at", "TIMESTAMP"},
+		{"last_reviewed", "TIMESTAMP"},
+		{"leitner_box", "INTEGER NOT NULL DEFAULT 1"},
+		{"leitner_due_at", "TIMESTAMP"},
+		{"fsrs_stability", "REAL NOT NULL DEFAULT 0"},
+		{"fsrs_difficulty", "REAL NOT NULL DEFAULT 0"},
+		{"fsrs_due_at", "TIMESTAMP"},
+		{"suspended", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"buried_until", "TIMESTAMP"},
+		{"lapses", "INTEGER NOT NULL DEFAULT 0"},
+		{"leech", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	}
+	for _, column := range columns {
+		if _, err := addColumnIfNotExists(ctx, repo.db, "user_words", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `UPDATE user_words SET due_at = COALESCE(last_asked, $1) WHERE due_at IS NULL`, time.Time{}); err != nil {
+		return err
+	}
+
+	_, err := repo.db.ExecContext(ctx, `
+UPDATE user_words SET
+    last_reviewed = COALESCE(last_reviewed, $1),
+    leitner_due_at = COALESCE(leitner_due_at, due_at),
+    fsrs_due_at = COALESCE(fsrs_due_at, due_at)
+WHERE last_reviewed IS NULL OR leitner_due_at IS NULL OR fsrs_due_at IS NULL`, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	if err = repo.migrateReverse(ctx); err != nil {
+		return err
+	}
+
+	if err = repo.migrateSense(ctx); err != nil {
+		return err
+	}
+
+	return repo.migrateOwner(ctx)
+}
+
+// migrateReverse adds reverse to the primary key of user_words, which means the
+// table has to be rebuilt, and gives every existing card a reverse card. They
+// are due from now on so they don't jump ahead of cards that are overdue.
+func (repo *UserWordsRepo) migrateReverse(ctx context.Context) error {
+	exists, err := columnExists(ctx, repo.db, "user_words", "reverse")
+	if err != nil || exists {
+		return err
+	}
+
+	now := time.Now().In(time.UTC)
+	return rebuildTable(ctx, repo.db, "user_words", userWordsSchema, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+INSERT INTO user_words (%s)
+SELECT user_id, 0, word, TRUE, 0, $1, $1, $2, 0, 0, $3, 1, $3, 0, 0, $3, 0, FALSE FROM user_words`, userWordColumns),
+			time.Time{}, defaultEaseFactor, now)
+		return err
+	})
+}
+
+// migrateSense adds sense to the primary key of user_words. Existing cards ask
+// the whole word.
+func (repo *UserWordsRepo) migrateSense(ctx context.Context) error {
+	exists, err := columnExists(ctx, repo.db, "user_words", "sense")
+	if err != nil || exists {
+		return err
+	}
+
+	return rebuildTable(ctx, repo.db, "user_words", userWordsSchema, nil)
+}
+
+// migrateOwner adds owner to user_words, which needs a rebuild for its foreign
+// key. Cards from before private words existed are all of shared ones.
+func (repo *UserWordsRepo) migrateOwner(ctx context.Context) error {
+	exists, err := columnExists(ctx, repo.db, "user_words", "owner")
+	if err != nil || exists {
+		return err
+	}
+
+	return rebuildTable(ctx, repo.db, "user_words", userWordsSchema, nil)
+}
+
 func (repo *UserWordsRepo) InsertBulkSingleUser(ctx context.Context, user int64, words []WordsModel) error {
-	userWords := make([]UserWordModel, 0, cap(words))
+	userWords := make([]UserWordModel, 0, 2*len(words))
 	for _, word := range words {
-		userWords = append(userWords, UserWordModel{
-			UserID:    user,
-			Word:      word.Word,
-			LastAsked: time.Time{},
-		})
+		userWords = append(userWords, newUserWord(user, word.Owner, word.Word, false), newUserWord(user, word.Owner, word.Word, true))
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = repo.insertBulk(ctx, tx, userWords); err != nil {
+		return err
+	}
+
+	if err = splitSenses(ctx, tx, "user_id", user); err != nil {
+		return err
 	}
 
-	return repo.InsertBulk(ctx, userWords)
+	return tx.Commit()
 }
 
 func (repo *UserWordsRepo) InsertBulkSingleWord(ctx context.Context, word string, users []int64) error {
-	userWords := make([]UserWordModel, 0, cap(users))
-	for _, user := range users {
-		userWords = append(userWords, UserWordModel{
-			UserID:    user,
-			Word:      word,
-			LastAsked: time.Time{},
-		})
+	return repo.insertBulk(ctx, repo.db, singleWord(0, word, users))
+}
+
+// InsertBulkSingleWordTx gives users cards of word of owner as part of tx. A
+// word with more than one sense gets a card per sense.
+func (repo *UserWordsRepo) InsertBulkSingleWordTx(ctx context.Context, tx *sql.Tx, owner int64, word string, users []int64) error {
+	if err := repo.insertBulk(ctx, tx, singleWord(owner, word, users)); err != nil {
+		return err
+	}
+
+	return repo.SplitSensesTx(ctx, tx, word)
+}
+
+// SplitSensesTx brings the cards of word in line with its senses as part of
+// tx, see splitSenses.
+func (repo *UserWordsRepo) SplitSensesTx(ctx context.Context, tx *sql.Tx, word string) error {
+	return splitSenses(ctx, tx, "word", word)
+}
+
+// splitSenses gives every card whose column is value a card per sense of its
+// word if the word has more than one. A card of the whole word becomes the
+// card of the first sense, so it keeps its progress and its reviews, and the
+// other senses get new cards. Cards of senses that no longer exist are
+// removed, and so are cards of the whole word added again for a word already
+// split.
+func splitSenses(ctx context.Context, e execer, column string, value interface{}) error {
+	stmts := []struct {
+		query string
+		args  []interface{}
+	}{
+		{fmt.Sprintf(`
+DELETE FROM user_words
+WHERE %s = $1 AND sense = 0 AND EXISTS (
+    SELECT 1 FROM user_words AS split
+    WHERE split.user_id = user_words.user_id AND split.word = user_words.word
+        AND split.reverse = user_words.reverse AND split.sense > 0)`, column), []interface{}{value}},
+		{fmt.Sprintf(`
+UPDATE reviews SET sense = 1
+WHERE %s = $1 AND sense = 0 AND EXISTS (
+    SELECT 1 FROM senses WHERE senses.owner = reviews.owner AND senses.word = reviews.word AND position = 2
+) AND EXISTS (
+    SELECT 1 FROM user_words
+    WHERE user_words.user_id = reviews.user_id AND user_words.owner = reviews.owner AND user_words.word = reviews.word
+        AND user_words.reverse = reviews.reverse AND user_words.sense = 0)`, column), []interface{}{value}},
+		{fmt.Sprintf(`
+UPDATE user_words SET sense = 1
+WHERE %s = $1 AND sense = 0 AND EXISTS (
+    SELECT 1 FROM senses WHERE senses.owner = user_words.owner AND senses.word = user_words.word AND position = 2)`, column),
+			[]interface{}{value}},
+		{fmt.Sprintf(`
+INSERT INTO user_words (user_id, owner, word, reverse, sense, last_asked, last_reviewed, due_at, leitner_due_at, fsrs_due_at)
+SELECT user_words.user_id, user_words.owner, user_words.word, user_words.reverse, senses.position, $1, $1, $1, $1, $1
+FROM user_words JOIN senses ON senses.owner = user_words.owner AND senses.word = user_words.word
+WHERE user_words.%s = $2 AND user_words.sense = 1 AND senses.position > 1
+ON CONFLICT DO NOTHING`, column), []interface{}{time.Time{}, value}},
+		{fmt.Sprintf(`
+DELETE FROM user_words
+WHERE %s = $1 AND sense > (SELECT COUNT(*) FROM senses WHERE senses.owner = user_words.owner AND senses.word = user_words.word)`,
+			column), []interface{}{value}},
+	}
+	for _, stmt := range stmts {
+		if _, err := e.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return err
+		}
 	}
 
-	return repo.InsertBulk(ctx, userWords)
+	return nil
 }
 
 func (repo *UserWordsRepo) InsertBulk(ctx context.Context, userWords []UserWordModel) error {
+	return repo.insertBulk(ctx, repo.db, userWords)
+}
+
+func (repo *UserWordsRepo) insertBulk(ctx context.Context, e execer, userWords []UserWordModel) error {
+	if len(userWords) == 0 {
+		return nil
+	}
+
 	valueStrings := make([]string, 0, len(userWords))
-	valueArgs := make([]interface{}, 0, len(userWords)*3)
+	valueArgs := make([]interface{}, 0, len(userWords)*18)
 	for _, userWord := range userWords {
-		valueStrings = append(valueStrings, "(?, ?, ?)")
+		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 		valueArgs = append(valueArgs, userWord.UserID)
+		valueArgs = append(valueArgs, userWord.Owner)
 		valueArgs = append(valueArgs, userWord.Word)
+		valueArgs = append(valueArgs, userWord.Reverse)
+		valueArgs = append(valueArgs, userWord.Sense)
 		valueArgs = append(valueArgs, userWord.LastAsked)
+		valueArgs = append(valueArgs, userWord.LastReviewed)
+		valueArgs = append(valueArgs, userWord.EaseFactor)
+		valueArgs = append(valueArgs, userWord.Interval)
+		valueArgs = append(valueArgs, userWord.Repetitions)
+		valueArgs = append(valueArgs, userWord.DueAt)
+		valueArgs = append(valueArgs, userWord.LeitnerBox)
+		valueArgs = append(valueArgs, userWord.LeitnerDueAt)
+		valueArgs = append(valueArgs, userWord.FSRSStability)
+		valueArgs = append(valueArgs, userWord.FSRSDifficulty)
+		valueArgs = append(valueArgs, userWord.FSRSDueAt)
+		valueArgs = append(valueArgs, userWord.Lapses)
+		valueArgs = append(valueArgs, userWord.Leech)
 	}
-	stmt := fmt.Sprintf("INSERT INTO user_words (user_id, word, last_asked) VALUES %s",
-		strings.Join(valueStrings, ","))
+	// cards of a word that was deleted and added again are still there.
+	stmt := fmt.Sprintf("INSERT INTO user_words (%s) VALUES %s ON CONFLICT DO NOTHING",
+		userWordColumns, strings.Join(valueStrings, ","))
 
-	_, err := repo.db.ExecContext(ctx, stmt, valueArgs...)
+	_, err := e.ExecContext(ctx, stmt, valueArgs...)
 	return err
 }
-func (repo *UserWordsRepo) GetRandomWord(ctx context.Context, userID int64) (*UserWordModel, error) {
-	var userWord UserWordModel
-	err := repo.db.QueryRowContext(ctx, `SELECT * FROM user_words WHERE user_id = $1 ORDER BY last_asked ASC LIMIT 1`, userID).
-		Scan(&userWord.UserID, &userWord.Word, &userWord.LastAsked)
+
+// singleWord returns the cards of word of owner for every user.
+func singleWord(owner int64, word string, users []int64) []UserWordModel {
+	userWords := make([]UserWordModel, 0, 2*len(users))
+	for _, user := range users {
+		userWords = append(userWords, newUserWord(user, owner, word, false), newUserWord(user, owner, word, true))
+	}
+
+	return userWords
+}
+
+// GetRandomWord returns the word scheduler wants to ask the user next from
+// queue. The review queue holds cards that have been reviewed before and are
+// due, most overdue first. The new queue holds cards that have never been
+// reviewed, in the order their words were added. Reverse cards are only
+// considered with withReverse and if the user has turned them on for the word.
+func (repo *UserWordsRepo) GetRandomWord(ctx context.Context, userID int64, scheduler Scheduler, withReverse bool, queue Queue) (*UserWordModel, error) {
+	var (
+		where   string
+		orderBy string
+	)
+	switch queue {
+	case QueueNew:
+		where = "last_reviewed = $4"
+		orderBy = "(SELECT created_at FROM words WHERE words.owner = user_words.owner AND words.word = user_words.word) ASC, reverse ASC, sense ASC"
+	default:
+		where = fmt.Sprintf("%s <= $3 AND last_reviewed != $4", scheduler.DueColumn())
+		orderBy = fmt.Sprintf("%s ASC, last_asked ASC", scheduler.DueColumn())
+	}
+
+	userWord, err := scanUserWord(repo.db.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT %s FROM user_words
+WHERE user_id = $1 AND (NOT reverse OR ($2 AND %s))
+    AND %s AND (buried_until IS NULL OR buried_until <= $3) AND %s
+ORDER BY %s LIMIT 1`, userWordColumns, reverseEnabled, inRotation, where, orderBy),
+		userID, withReverse, time.Now().In(time.UTC), time.Time{}))
 	if err != nil {
 		return nil, err
 	}
@@ -95,10 +407,212 @@ func (repo *UserWordsRepo) GetRandomWord(ctx context.Context, userID int64) (*Us
 	// TODO this should be transaction or should be handled in a single query.
 	// TODO I don't know if the latter is possible with sqlite.
 	// TODO but this solution is good enough and i'm sticking to it :)
-	_, err = repo.db.ExecContext(ctx, `UPDATE user_words SET last_asked = $1 WHERE user_id = $2 AND word = $3`, time.Now().In(time.UTC), userID, userWord.Word)
+	if err = repo.MarkAsked(ctx, userWord); err != nil {
+		return nil, err
+	}
+
+	return userWord, nil
+}
+
+// MarkAsked records that userWord has just been shown to the user.
+func (repo *UserWordsRepo) MarkAsked(ctx context.Context, userWord *UserWordModel) error {
+	userWord.LastAsked = time.Now().In(time.UTC)
+	_, err := repo.db.ExecContext(ctx, `UPDATE user_words SET last_asked = $1 WHERE user_id = $2 AND word = $3 AND reverse = $4 AND sense = $5`,
+		userWord.LastAsked, userWord.UserID, userWord.Word, userWord.Reverse, userWord.Sense)
+	return err
+}
+
+// CountDue returns how many cards of the user are due at now according to
+// scheduler: the cards of the review queue and up to newLimit cards of the new
+// queue, see GetRandomWord. Reverse cards count only if the user has turned
+// them on, and suspended, buried and leech cards are left out.
+func (repo *UserWordsRepo) CountDue(ctx context.Context, userID int64, scheduler Scheduler, now time.Time, newLimit int) (int, error) {
+	var count int
+	err := repo.db.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FILTER (WHERE last_reviewed != $1) + MIN(COUNT(*) FILTER (WHERE last_reviewed = $1), $2) FROM user_words
+WHERE user_id = $3 AND %s <= $4 AND (NOT reverse OR %s)
+    AND %s AND (buried_until IS NULL OR buried_until <= $4)`,
+		scheduler.DueColumn(), reverseEnabled, inRotation), time.Time{}, newLimit, userID, now.In(time.UTC)).Scan(&count)
+
+	return count, err
+}
+
+// DueDates returns when each card of the user is due according to scheduler,
+// leaving out cards that are not in rotation and reverse cards if the user
+// hasn't turned them on.
+func (repo *UserWordsRepo) DueDates(ctx context.Context, userID int64, scheduler Scheduler) ([]time.Time, error) {
+	rows, err := repo.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT %s FROM user_words
+WHERE user_id = $1 AND (NOT reverse OR %s) AND %s`,
+		scheduler.DueColumn(), reverseEnabled, inRotation), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []time.Time
+	for rows.Next() {
+		var due sql.NullTime
+		if err = rows.Scan(&due); err != nil {
+			return nil, err
+		}
+		list = append(list, due.Time)
+	}
+
+	return list, rows.Err()
+}
+
+func (repo *UserWordsRepo) Get(ctx context.Context, userID int64, word string, reverse bool, sense int) (*UserWordModel, error) {
+	return scanUserWord(repo.db.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT %s FROM user_words WHERE user_id = $1 AND word = $2 AND reverse = $3 AND sense = $4`, userWordColumns),
+		userID, word, reverse, sense))
+}
+
+// GetCards returns every card the user has of word.
+func (repo *UserWordsRepo) GetCards(ctx context.Context, userID int64, word string) ([]UserWordModel, error) {
+	rows, err := repo.db.QueryContext(ctx,
+		fmt.Sprintf(`SELECT %s FROM user_words WHERE user_id = $1 AND word = $2 ORDER BY reverse, sense`, userWordColumns),
+		userID, word)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []UserWordModel
+	for rows.Next() {
+		res, err := scanUserWord(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *res)
+	}
+
+	return list, rows.Err()
+}
+
+// UpdateSchedule stores the scheduling state of userWord for every scheduler.
+func (repo *UserWordsRepo) UpdateSchedule(ctx context.Context, userWord *UserWordModel) error {
+	_, err := repo.db.ExecContext(ctx, `
+UPDATE user_words SET
+    last_reviewed = $1, ease_factor = $2, interval_days = $3, repetitions = $4, due_at = $5,
+    leitner_box = $6, leitner_due_at = $7, fsrs_stability = $8, fsrs_difficulty = $9, fsrs_due_at = $10,
+    lapses = $11, leech = $12
+WHERE user_id = $13 AND word = $14 AND reverse = $15 AND sense = $16`,
+		userWord.LastReviewed, userWord.EaseFactor, userWord.Interval, userWord.Repetitions, userWord.DueAt,
+		userWord.LeitnerBox, userWord.LeitnerDueAt, userWord.FSRSStability, userWord.FSRSDifficulty, userWord.FSRSDueAt,
+		userWord.Lapses, userWord.Leech, userWord.UserID, userWord.Word, userWord.Reverse, userWord.Sense)
+	return err
+}
+
+// Suspend takes every card of word out of rotation until Unsuspend is called.
+// It returns sql.ErrNoRows if the user doesn't have word.
+func (repo *UserWordsRepo) Suspend(ctx context.Context, userID int64, word string) error {
+	return repo.setWord(ctx, userID, word, "suspended = $1", true)
+}
+
+// Bury hides every card of word until until.
+func (repo *UserWordsRepo) Bury(ctx context.Context, userID int64, word string, until time.Time) error {
+	return repo.setWord(ctx, userID, word, "buried_until = $1", until.In(time.UTC))
+}
+
+// Unsuspend puts a suspended or buried word back into rotation.
+func (repo *UserWordsRepo) Unsuspend(ctx context.Context, userID int64, word string) error {
+	return repo.setWord(ctx, userID, word, "suspended = $1, buried_until = NULL", false)
+}
+
+// setWord applies set, which uses $1 for value, to every card of word. It
+// returns sql.ErrNoRows if the user doesn't have word.
+func (repo *UserWordsRepo) setWord(ctx context.Context, userID int64, word, set string, value interface{}) error {
+	res, err := repo.db.ExecContext(ctx, fmt.Sprintf("UPDATE user_words SET %s WHERE user_id = $2 AND word = $3", set), value, userID, word)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListSuspended returns the words of the user that are suspended or buried
+// past now. All cards of a word are always suspended together, so only the
+// first forward card is looked at.
+func (repo *UserWordsRepo) ListSuspended(ctx context.Context, userID int64, now time.Time) ([]SuspendedWordModel, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+SELECT word, suspended, buried_until FROM user_words
+WHERE user_id = $1 AND NOT reverse AND sense <= 1 AND (suspended OR buried_until > $2) AND `+visible+`
+ORDER BY word`, userID, now.In(time.UTC))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var list []SuspendedWordModel
+	for rows.Next() {
+		var (
+			res         SuspendedWordModel
+			buriedUntil sql.NullTime
+		)
+		if err = rows.Scan(&res.Word, &res.Suspended, &buriedUntil); err != nil {
+			return nil, err
+		}
+		if buriedUntil.Time.After(now) {
+			res.BuriedUntil = buriedUntil.Time
+		}
+		list = append(list, res)
+	}
+
+	return list, rows.Err()
+}
+
+// GetLeech returns the leech of the user that was asked the longest time ago.
+func (repo *UserWordsRepo) GetLeech(ctx context.Context, userID int64) (*UserWordModel, error) {
+	return scanUserWord(repo.db.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT %s FROM user_words WHERE user_id = $1 AND leech AND NOT suspended AND %s AND %s
+ORDER BY last_asked ASC LIMIT 1`, userWordColumns, visible, subscribed), userID))
+}
+
+func (repo *UserWordsRepo) CountLeeches(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := repo.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM user_words WHERE user_id = $1 AND leech AND NOT suspended AND %s AND %s", visible, subscribed), userID).
+		Scan(&count)
+
+	return count, err
+}
+
+// IsNew reports whether the card has never been reviewed.
+func (userWord *UserWordModel) IsNew() bool {
+	return userWord.LastReviewed.IsZero()
+}
+
+func newUserWord(user, owner int64, word string, reverse bool) UserWordModel {
+	return UserWordModel{
+		UserID:       user,
+		Owner:        owner,
+		Word:         word,
+		Reverse:      reverse,
+		LastAsked:    time.Time{},
+		LastReviewed: time.Time{},
+		EaseFactor:   defaultEaseFactor,
+		DueAt:        time.Time{},
+		LeitnerBox:   1,
+		LeitnerDueAt: time.Time{},
+		FSRSDueAt:    time.Time{},
+	}
+}
+
+func scanUserWord(row interface{ Scan(...any) error }) (*UserWordModel, error) {
+	var userWord UserWordModel
+	if err := row.Scan(&userWord.UserID, &userWord.Owner, &userWord.Word, &userWord.Reverse, &userWord.Sense, &userWord.LastAsked, &userWord.LastReviewed,
+		&userWord.EaseFactor, &userWord.Interval, &userWord.Repetitions, &userWord.DueAt,
+		&userWord.LeitnerBox, &userWord.LeitnerDueAt,
+		&userWord.FSRSStability, &userWord.FSRSDifficulty, &userWord.FSRSDueAt,
+		&userWord.Lapses, &userWord.Leech); err != nil {
+		return nil, err
+	}
 
 	return &userWord, nil
 }
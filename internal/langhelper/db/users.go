@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	_ "github.com/mattn/go-sqlite3"
 	"time"
 )
@@ -12,6 +13,21 @@ type (
 		UserID    int64
 		CreatedAt time.Time
 	}
+	// ReminderModel holds when and where a user wants to be reminded to review.
+	// Times of day are "15:04" in the user's timezone and empty when unset.
+	ReminderModel struct {
+		UserID       int64
+		Timezone     string
+		RemindAt     string
+		QuietStart   string
+		QuietEnd     string
+		LastReminded time.Time
+	}
+	// LimitsModel is how many new cards and reviews a user wants per day.
+	LimitsModel struct {
+		New     int
+		Reviews int
+	}
 	UsersRepo struct {
 		db *sql.DB
 	}
@@ -31,10 +47,51 @@ func (repo *UsersRepo) init(ctx context.Context) error {
 	_, err := repo.db.ExecContext(ctx, `
 CREATE TABLE IF NOT EXISTS users(
     user_id BIGINT PRIMARY KEY,
-    created_at TIMESTAMP
+    created_at TIMESTAMP,
+    scheduler TEXT NOT NULL DEFAULT 'sm2',
+    reverse_cards BOOLEAN NOT NULL DEFAULT FALSE,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    remind_at TEXT NOT NULL DEFAULT '',
+    quiet_start TEXT NOT NULL DEFAULT '',
+    quiet_end TEXT NOT NULL DEFAULT '',
+    last_reminded TIMESTAMP,
+    blocked BOOLEAN NOT NULL DEFAULT FALSE,
+    daily_goal INTEGER NOT NULL DEFAULT 0,
+    streak INTEGER NOT NULL DEFAULT 0,
+    streak_freezes INTEGER NOT NULL DEFAULT 0,
+    streak_last_day TEXT NOT NULL DEFAULT '',
+    new_limit INTEGER NOT NULL DEFAULT 20,
+    review_limit INTEGER NOT NULL DEFAULT 200,
+    leech_threshold INTEGER NOT NULL DEFAULT 8
 )`)
+	if err != nil {
+		return err
+	}
 
-	return err
+	columns := []struct{ name, definition string }{
+		{"scheduler", "TEXT NOT NULL DEFAULT 'sm2'"},
+		{"reverse_cards", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"timezone", "TEXT NOT NULL DEFAULT 'UTC'"},
+		{"remind_at", "TEXT NOT NULL DEFAULT ''"},
+		{"quiet_start", "TEXT NOT NULL DEFAULT ''"},
+		{"quiet_end", "TEXT NOT NULL DEFAULT ''"},
+		{"last_reminded", "TIMESTAMP"},
+		{"blocked", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"daily_goal", "INTEGER NOT NULL DEFAULT 0"},
+		{"streak", "INTEGER NOT NULL DEFAULT 0"},
+		{"streak_freezes", "INTEGER NOT NULL DEFAULT 0"},
+		{"streak_last_day", "TEXT NOT NULL DEFAULT ''"},
+		{"new_limit", "INTEGER NOT NULL DEFAULT 20"},
+		{"review_limit", "INTEGER NOT NULL DEFAULT 200"},
+		{"leech_threshold", "INTEGER NOT NULL DEFAULT 8"},
+	}
+	for _, column := range columns {
+		if _, err = addColumnIfNotExists(ctx, repo.db, "users", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (repo *UsersRepo) Insert(ctx context.Context, user UsersModel) error {
@@ -42,6 +99,176 @@ func (repo *UsersRepo) Insert(ctx context.Context, user UsersModel) error {
 	return err
 }
 
+func (repo *UsersRepo) GetScheduler(ctx context.Context, userID int64) (Scheduler, error) {
+	var name string
+	if err := repo.db.QueryRowContext(ctx, "SELECT scheduler FROM users WHERE user_id = $1", userID).
+		Scan(&name); err != nil {
+		return nil, err
+	}
+
+	return GetScheduler(name), nil
+}
+
+func (repo *UsersRepo) SetScheduler(ctx context.Context, userID int64, scheduler string) error {
+	return repo.set(ctx, userID, "scheduler", scheduler)
+}
+
+func (repo *UsersRepo) GetReverseCards(ctx context.Context, userID int64) (bool, error) {
+	var enabled bool
+	if err := repo.db.QueryRowContext(ctx, "SELECT reverse_cards FROM users WHERE user_id = $1", userID).
+		Scan(&enabled); err != nil {
+		return false, err
+	}
+
+	return enabled, nil
+}
+
+func (repo *UsersRepo) SetReverseCards(ctx context.Context, userID int64, enabled bool) error {
+	return repo.set(ctx, userID, "reverse_cards", enabled)
+}
+
+// GetLocation returns the timezone of the user, UTC if it is not valid.
+func (repo *UsersRepo) GetLocation(ctx context.Context, userID int64) (*time.Location, error) {
+	var timezone string
+	if err := repo.db.QueryRowContext(ctx, "SELECT timezone FROM users WHERE user_id = $1", userID).
+		Scan(&timezone); err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+
+	return loc, nil
+}
+
+func (repo *UsersRepo) SetTimezone(ctx context.Context, userID int64, timezone string) error {
+	return repo.set(ctx, userID, "timezone", timezone)
+}
+
+func (repo *UsersRepo) SetRemindAt(ctx context.Context, userID int64, remindAt string) error {
+	return repo.set(ctx, userID, "remind_at", remindAt)
+}
+
+func (repo *UsersRepo) SetQuietHours(ctx context.Context, userID int64, start, end string) error {
+	if err := repo.set(ctx, userID, "quiet_start", start); err != nil {
+		return err
+	}
+
+	return repo.set(ctx, userID, "quiet_end", end)
+}
+
+func (repo *UsersRepo) SetLastReminded(ctx context.Context, userID int64, at time.Time) error {
+	return repo.set(ctx, userID, "last_reminded", at)
+}
+
+// SetBlocked marks whether the user has blocked the bot. Blocked users are not
+// reminded.
+func (repo *UsersRepo) SetBlocked(ctx context.Context, userID int64, blocked bool) error {
+	return repo.set(ctx, userID, "blocked", blocked)
+}
+
+func (repo *UsersRepo) GetReminder(ctx context.Context, userID int64) (*ReminderModel, error) {
+	return scanReminder(repo.db.QueryRowContext(ctx,
+		"SELECT user_id, timezone, remind_at, quiet_start, quiet_end, last_reminded FROM users WHERE user_id = $1", userID))
+}
+
+// ListReminders returns the reminder settings of users who have a reminder and
+// haven't blocked the bot.
+func (repo *UsersRepo) ListReminders(ctx context.Context) ([]ReminderModel, error) {
+	rows, err := repo.db.QueryContext(ctx,
+		"SELECT user_id, timezone, remind_at, quiet_start, quiet_end, last_reminded FROM users WHERE remind_at != '' AND NOT blocked")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ReminderModel
+	for rows.Next() {
+		res, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *res)
+	}
+
+	return list, rows.Err()
+}
+
+func (repo *UsersRepo) SetDailyGoal(ctx context.Context, userID int64, goal int) error {
+	return repo.set(ctx, userID, "daily_goal", goal)
+}
+
+func (repo *UsersRepo) GetStreak(ctx context.Context, userID int64) (*StreakModel, error) {
+	var res StreakModel
+	if err := repo.db.QueryRowContext(ctx,
+		"SELECT user_id, daily_goal, streak, streak_freezes, streak_last_day FROM users WHERE user_id = $1", userID).
+		Scan(&res.UserID, &res.DailyGoal, &res.Streak, &res.Freezes, &res.LastDay); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (repo *UsersRepo) UpdateStreak(ctx context.Context, streak *StreakModel) error {
+	_, err := repo.db.ExecContext(ctx,
+		"UPDATE users SET streak = $1, streak_freezes = $2, streak_last_day = $3 WHERE user_id = $4",
+		streak.Streak, streak.Freezes, streak.LastDay, streak.UserID)
+	return err
+}
+
+func (repo *UsersRepo) GetLimits(ctx context.Context, userID int64) (*LimitsModel, error) {
+	var res LimitsModel
+	if err := repo.db.QueryRowContext(ctx, "SELECT new_limit, review_limit FROM users WHERE user_id = $1", userID).
+		Scan(&res.New, &res.Reviews); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (repo *UsersRepo) SetNewLimit(ctx context.Context, userID int64, limit int) error {
+	return repo.set(ctx, userID, "new_limit", limit)
+}
+
+func (repo *UsersRepo) SetReviewLimit(ctx context.Context, userID int64, limit int) error {
+	return repo.set(ctx, userID, "review_limit", limit)
+}
+
+// GetLeechThreshold returns after how many lapses a card becomes a leech, 0
+// if leeches are turned off.
+func (repo *UsersRepo) GetLeechThreshold(ctx context.Context, userID int64) (int, error) {
+	var threshold int
+	if err := repo.db.QueryRowContext(ctx, "SELECT leech_threshold FROM users WHERE user_id = $1", userID).
+		Scan(&threshold); err != nil {
+		return 0, err
+	}
+
+	return threshold, nil
+}
+
+func (repo *UsersRepo) SetLeechThreshold(ctx context.Context, userID int64, threshold int) error {
+	return repo.set(ctx, userID, "leech_threshold", threshold)
+}
+
+// set updates a single setting column of a user. It returns sql.ErrNoRows if
+// the user hasn't started the bot.
+func (repo *UsersRepo) set(ctx context.Context, userID int64, column string, value interface{}) error {
+	res, err := repo.db.ExecContext(ctx, fmt.Sprintf("UPDATE users SET %s = $1 WHERE user_id = $2", column), value, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
 func (repo *UsersRepo) ListIDs(ctx context.Context) ([]int64, error) {
 	rows, err := repo.db.Query("SELECT user_id FROM users")
 	if err != nil {
@@ -64,3 +291,16 @@ func (repo *UsersRepo) ListIDs(ctx context.Context) ([]int64, error) {
 
 	return list, nil
 }
+
+func scanReminder(row interface{ Scan(...any) error }) (*ReminderModel, error) {
+	var (
+		res          ReminderModel
+		lastReminded sql.NullTime
+	)
+	if err := row.Scan(&res.UserID, &res.Timezone, &res.RemindAt, &res.QuietStart, &res.QuietEnd, &lastReminded); err != nil {
+		return nil, err
+	}
+	res.LastReminded = lastReminded.Time
+
+	return &res, nil
+}
@@ -3,17 +3,121 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
 	_ "github.com/mattn/go-sqlite3"
+	"strings"
 	"time"
 )
 
+const (
+	wordColumns = `word, meaning, file_id, created_at, pos, ipa, source_chat_id, source_message_id, deleted_at, owner,
+    source_lang, target_lang, COALESCE((SELECT title FROM chats WHERE chats.chat_id = words.source_chat_id), '')`
+
+	// visible is the condition for user_words rows whose word hasn't been
+	// deleted.
+	visible = `EXISTS (
+    SELECT 1 FROM words WHERE words.owner = user_words.owner AND words.word = user_words.word AND deleted_at IS NULL)`
+
+	// wordsSchema is the schema of words, with %s for the table name so it
+	// can be rebuilt. Every user can have a private word of the same name as
+	// a shared one, which has owner 0. word_key is lang.Key of the word, which
+	// lookups and new words are matched by, and folded_key lang.FoldedKey,
+	// which lookups fall back to.
+	wordsSchema = `
+CREATE TABLE IF NOT EXISTS %s(
+    owner BIGINT NOT NULL DEFAULT 0,
+    word TEXT NOT NULL,
+    meaning TEXT,
+    file_id TEXT,
+    created_at TIMESTAMP,
+    pos TEXT NOT NULL DEFAULT '',
+    ipa TEXT NOT NULL DEFAULT '',
+    source_chat_id BIGINT NOT NULL DEFAULT 0,
+    source_message_id INTEGER NOT NULL DEFAULT 0,
+    deleted_at TIMESTAMP,
+    source_lang TEXT NOT NULL DEFAULT 'en',
+    target_lang TEXT NOT NULL DEFAULT '',
+    word_key TEXT NOT NULL DEFAULT '',
+    folded_key TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY(owner, word)
+)`
+
+	// wordFieldsSchema is the schema of word_fields, which holds the list
+	// fields of a word: synonyms, antonyms, examples and tags, in the order
+	// they were given.
+	wordFieldsSchema = `
+CREATE TABLE IF NOT EXISTS %s(
+    owner BIGINT NOT NULL DEFAULT 0,
+    word TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY(owner, word, kind, position),
+    FOREIGN KEY(owner, word) REFERENCES words (owner, word)
+)`
+
+	// kinds of rows in word_fields
+	fieldSynonym = "syn"
+	fieldAntonym = "ant"
+	fieldExample = "ex"
+	fieldTag     = "tag"
+)
+
+// ErrDuplicate is returned when a word that already exists is added.
+var ErrDuplicate = errors.New("word already exists")
+
+// homographMarks number words that share a spelling. The first one has none.
+var homographMarks = []string{"²", "³", "⁴", "⁵", "⁶", "⁷", "⁸", "⁹"}
+
 type (
 	WordsModel struct {
-		Word      string
+		// Owner is the user a private word belongs to, zero for shared
+		// words.
+		Owner int64
+		Word  string
+		// Meaning is every sense of the word, numbered if there is more than
+		// one.
 		Meaning   string
 		FileID    string
 		CreatedAt time.Time
+		// Senses are only loaded by GetByWords. When a word is saved without
+		// them they are made from Meaning.
+		Senses []SenseModel
+
+		PartOfSpeech string
+		IPA          string
+		// Synonyms, Antonyms, Examples and Tags are only loaded by GetByWords.
+		Synonyms []string
+		Antonyms []string
+		Examples []string
+		Tags     []string
+
+		// SourceChatID and SourceMessageID are the post the word was added
+		// with, zero for words added before they were recorded.
+		SourceChatID    int64
+		SourceMessageID int
+		// SourceChatTitle is the title of the source chat, empty for private
+		// chats and ones the bot hasn't seen since titles were recorded.
+		SourceChatTitle string
+		// DeletedAt is set when the word has been deleted but its history is
+		// kept.
+		DeletedAt time.Time
+
+		// SourceLang is the BCP-47 tag of the language of the word and
+		// TargetLang the one of its meaning, empty if it isn't known. See
+		// package lang for how they are used.
+		SourceLang string
+		TargetLang string
+	}
+	// KeyCollision is words of Owner that have the same Key.
+	KeyCollision struct {
+		Owner int64
+		Key   string
+		Words []string
 	}
+
 	WordsRepo struct {
 		db *sql.DB
 	}
@@ -30,24 +134,497 @@ func NewWordsRepo(db *sql.DB) (*WordsRepo, error) {
 }
 
 func (repo *WordsRepo) init(ctx context.Context) error {
-	_, err := repo.db.ExecContext(ctx, `
-CREATE TABLE IF NOT EXISTS words(
-    word TEXT PRIMARY KEY,
-    meaning TEXT,
-    file_id TEXT,
-	created_at TIMESTAMP
-)`)
+	tables := []struct{ name, schema string }{
+		{"words", wordsSchema},
+		{"word_fields", wordFieldsSchema},
+		{"senses", sensesSchema},
+	}
+	for _, table := range tables {
+		if _, err := repo.db.ExecContext(ctx, fmt.Sprintf(table.schema, table.name)); err != nil {
+			return err
+		}
+	}
+
+	columns := []struct{ name, definition string }{
+		{"pos", "TEXT NOT NULL DEFAULT ''"},
+		{"ipa", "TEXT NOT NULL DEFAULT ''"},
+		{"source_chat_id", "BIGINT NOT NULL DEFAULT 0"},
+		{"source_message_id", "INTEGER NOT NULL DEFAULT 0"},
+		{"deleted_at", "TIMESTAMP"},
+		{"source_lang", "TEXT NOT NULL DEFAULT 'en'"},
+		{"target_lang", "TEXT NOT NULL DEFAULT ''"},
+		{"word_key", "TEXT NOT NULL DEFAULT ''"},
+		{"folded_key", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, column := range columns {
+		if _, err := addColumnIfNotExists(ctx, repo.db, "words", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	// words from before private ones existed are all shared.
+	for _, table := range tables {
+		exists, err := columnExists(ctx, repo.db, table.name, "owner")
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		if err = rebuildTable(ctx, repo.db, table.name, table.schema, nil); err != nil {
+			return err
+		}
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `
+CREATE INDEX IF NOT EXISTS words_key ON words (owner, word_key);
+CREATE INDEX IF NOT EXISTS words_folded_key ON words (owner, folded_key)`); err != nil {
+		return err
+	}
+
+	return repo.migrateSenses(ctx)
+}
+
+// Rekey stores every word the way lang.Lower gives it now, with the keys
+// lang.Key and lang.FoldedKey give. That is all of them the first time, and
+// the ones whose language is normalized or folded differently since, like
+// when FoldDiacritics is set. A word is renamed with its cards and reviews,
+// unless its owner already has a word of the new name, so it has to run once
+// the other repos have brought their tables up to date. Words may end up with
+// the same key, see Collisions.
+func (repo *WordsRepo) Rekey(ctx context.Context) error {
+	rows, err := repo.db.QueryContext(ctx, "SELECT owner, word, source_lang, word_key, folded_key FROM words")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type staleWord struct {
+		owner       int64
+		word, name  string
+		key, folded string
+	}
+	var stale []staleWord
+	for rows.Next() {
+		var (
+			w                staleWord
+			language, stored string
+			storedFolded     string
+		)
+		if err = rows.Scan(&w.owner, &w.word, &language, &stored, &storedFolded); err != nil {
+			return err
+		}
+		w.name = lang.Lower(lang.Of(language), w.word)
+		w.key, w.folded = wordKey(language, w.word), foldedKey(language, w.word)
+		if w.name != w.word || w.key != stored || w.folded != storedFolded {
+			stale = append(stale, w)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, w := range stale {
+		if w.name != w.word {
+			var exists bool
+			if err = tx.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM words WHERE owner = $1 AND word = $2", w.owner, w.name).
+				Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				for _, table := range []string{"words", "word_fields", "senses", "deck_words", "user_words", "reviews"} {
+					if _, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET word = $1 WHERE owner = $2 AND word = $3", table),
+						w.name, w.owner, w.word); err != nil {
+						return err
+					}
+				}
+				w.word = w.name
+			}
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE words SET word_key = $1, folded_key = $2 WHERE owner = $3 AND word = $4",
+			w.key, w.folded, w.owner, w.word); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Collisions returns the words that have the same key as another word of the
+// same owner, which happens when words stored before keys existed, or before
+// the way keys are made changed, were typed differently. Only the first of
+// them is found by lookups, the others have to be renamed or deleted.
+func (repo *WordsRepo) Collisions(ctx context.Context) ([]KeyCollision, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+SELECT owner, word_key, GROUP_CONCAT(word, char(10)) FROM words WHERE deleted_at IS NULL
+GROUP BY owner, word_key HAVING COUNT(*) > 1 ORDER BY owner, word_key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []KeyCollision
+	for rows.Next() {
+		var (
+			res   KeyCollision
+			words string
+		)
+		if err = rows.Scan(&res.Owner, &res.Key, &words); err != nil {
+			return nil, err
+		}
+		res.Words = strings.Split(words, "\n")
+		list = append(list, res)
+	}
 
-	return err
+	return list, rows.Err()
 }
 
 func (repo *WordsRepo) Insert(ctx context.Context, model WordsModel) error {
-	_, err := repo.db.ExecContext(ctx, "INSERT INTO words (word, meaning, file_id, created_at) VALUES($1, $2, $3, $4)", model.Word, model.Meaning, model.FileID, model.CreatedAt)
-	return err
+	tx, err := repo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = repo.InsertTx(ctx, tx, model); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// BeginTx starts a transaction for the *Tx methods of the repos.
+func (repo *WordsRepo) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return repo.db.BeginTx(ctx, nil)
+}
+
+// InsertTx is Insert as part of tx. A word that has been deleted is brought
+// back with the new meaning, everything else that exists is ErrDuplicate.
+func (repo *WordsRepo) InsertTx(ctx context.Context, tx *sql.Tx, model WordsModel) error {
+	res, err := tx.ExecContext(ctx, `
+INSERT INTO words (owner, word, meaning, file_id, created_at, pos, ipa, source_chat_id, source_message_id,
+    source_lang, target_lang, word_key, folded_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (owner, word) DO UPDATE SET
+    meaning = excluded.meaning, file_id = excluded.file_id, pos = excluded.pos, ipa = excluded.ipa,
+    source_chat_id = excluded.source_chat_id, source_message_id = excluded.source_message_id, deleted_at = NULL,
+    source_lang = excluded.source_lang, target_lang = excluded.target_lang,
+    word_key = excluded.word_key, folded_key = excluded.folded_key
+WHERE words.deleted_at IS NOT NULL`,
+		model.Owner, model.Word, model.Meaning, model.FileID, model.CreatedAt, model.PartOfSpeech, model.IPA,
+		model.SourceChatID, model.SourceMessageID, model.SourceLang, model.TargetLang,
+		wordKey(model.SourceLang, model.Word), foldedKey(model.SourceLang, model.Word))
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDuplicate
+	}
+
+	// a private word takes over the cards its owner had of a shared word of
+	// the same name, with their progress and reviews.
+	if model.Owner != 0 {
+		for _, table := range []string{"user_words", "reviews"} {
+			if _, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET owner = $1 WHERE user_id = $1 AND word = $2", table),
+				model.Owner, model.Word); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = repo.setSenses(ctx, tx, model.Word, model); err != nil {
+		return err
+	}
+
+	return repo.setFields(ctx, tx, model.Word, model)
+}
+
+// Update is UpdateTx in a transaction of its own.
+func (repo *WordsRepo) Update(ctx context.Context, word string, model WordsModel) error {
+	tx, err := repo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = repo.UpdateTx(ctx, tx, word, model); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MergeTx adds model, whose word already exists, according to policy as part
+// of tx and returns the word it was stored as. The cards of the existing word
+// are left alone, so nobody loses their progress, but the senses may have
+// changed, see UserWordsRepo.SplitSensesTx. DuplicateReject returns
+// ErrDuplicate, and so do DuplicateReplace and DuplicateAppend when the word
+// was posted in another chat than model, as only that chat may change it.
+func (repo *WordsRepo) MergeTx(ctx context.Context, tx *sql.Tx, model WordsModel, policy DuplicatePolicy) (string, error) {
+	if policy == DuplicateReplace || policy == DuplicateAppend {
+		source, err := repo.SourceChatTx(ctx, tx, model.Owner, model.Word)
+		if err != nil {
+			return "", err
+		}
+		if source != 0 && source != model.SourceChatID {
+			return "", ErrDuplicate
+		}
+	}
+
+	switch policy {
+	case DuplicateReplace:
+		_, err := tx.ExecContext(ctx, `
+UPDATE words SET
+    meaning = $1, file_id = CASE WHEN $2 = '' THEN file_id ELSE $2 END, pos = $3, ipa = $4,
+    source_chat_id = $5, source_message_id = $6, source_lang = $7, target_lang = $8, word_key = $9, folded_key = $10
+WHERE owner = $11 AND word = $12`,
+			model.Meaning, model.FileID, model.PartOfSpeech, model.IPA, model.SourceChatID, model.SourceMessageID,
+			model.SourceLang, model.TargetLang, wordKey(model.SourceLang, model.Word), foldedKey(model.SourceLang, model.Word),
+			model.Owner, model.Word)
+		if err != nil {
+			return "", err
+		}
+
+		if err = repo.setSenses(ctx, tx, model.Word, model); err != nil {
+			return "", err
+		}
+
+		return model.Word, repo.setFields(ctx, tx, model.Word, model)
+	case DuplicateAppend:
+		senses, err := getSenses(ctx, tx, model.Owner, model.Word)
+		if err != nil {
+			return "", err
+		}
+
+		added := sensesOf(model)
+		for i := range added {
+			if added[i].FileID == "" {
+				added[i].FileID = model.FileID
+			}
+		}
+		if err = repo.insertSenses(ctx, tx, model.Owner, model.Word, added, len(senses)+1); err != nil {
+			return "", err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+UPDATE words SET
+    meaning = $1, file_id = CASE WHEN file_id = '' THEN $2 ELSE file_id END,
+    pos = CASE WHEN pos = '' THEN $3 ELSE pos END, ipa = CASE WHEN ipa = '' THEN $4 ELSE ipa END
+WHERE owner = $5 AND word = $6`,
+			JoinSenses(append(senses, added...)), model.FileID, model.PartOfSpeech, model.IPA, model.Owner, model.Word)
+		if err != nil {
+			return "", err
+		}
+
+		return model.Word, repo.appendFields(ctx, tx, model.Word, model)
+	case DuplicateHomograph:
+		word := model.Word
+		for _, mark := range homographMarks {
+			model.Word = word + mark
+			err := repo.InsertTx(ctx, tx, model)
+			if errors.Is(err, ErrDuplicate) {
+				continue
+			} else if err != nil {
+				return "", err
+			}
+
+			return model.Word, nil
+		}
+
+		return "", ErrDuplicate
+	default:
+		return "", ErrDuplicate
+	}
+}
+
+// UpdateTx replaces word of model.Owner with model as part of tx. If the word
+// itself changes its cards and reviews follow it. It returns sql.ErrNoRows if
+// word doesn't exist and ErrDuplicate if it is renamed to a word that does,
+// or to one with the same key.
+func (repo *WordsRepo) UpdateTx(ctx context.Context, tx *sql.Tx, word string, model WordsModel) error {
+	key := wordKey(model.SourceLang, model.Word)
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM words WHERE owner = $1 AND (word = $2 OR word_key = $3) AND word != $4",
+		model.Owner, model.Word, key, word).Scan(&exists); err != nil {
+		return err
+	} else if exists {
+		return ErrDuplicate
+	}
+
+	res, err := tx.ExecContext(ctx, `
+UPDATE words SET word = $1, meaning = $2, file_id = $3, pos = $4, ipa = $5, source_lang = $6, target_lang = $7,
+    word_key = $8, folded_key = $9
+WHERE owner = $10 AND word = $11 AND deleted_at IS NULL`,
+		model.Word, model.Meaning, model.FileID, model.PartOfSpeech, model.IPA, model.SourceLang, model.TargetLang, key,
+		foldedKey(model.SourceLang, model.Word), model.Owner, word)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if model.Word != word {
+		for _, table := range []string{"user_words", "reviews", "deck_words"} {
+			if _, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET word = $1 WHERE owner = $2 AND word = $3", table),
+				model.Word, model.Owner, word); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = repo.setSenses(ctx, tx, word, model); err != nil {
+		return err
+	}
+
+	return repo.setFields(ctx, tx, word, model)
+}
+
+// setFields replaces the list fields of word with the ones of model, which
+// may have a new name for it.
+func (repo *WordsRepo) setFields(ctx context.Context, tx *sql.Tx, word string, model WordsModel) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM word_fields WHERE owner = $1 AND word = $2", model.Owner, word); err != nil {
+		return err
+	}
+
+	fields := map[string][]string{
+		fieldSynonym: model.Synonyms,
+		fieldAntonym: model.Antonyms,
+		fieldExample: model.Examples,
+		fieldTag:     model.Tags,
+	}
+	for kind, values := range fields {
+		for i, value := range values {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO word_fields (owner, word, kind, position, value) VALUES ($1, $2, $3, $4, $5)",
+				model.Owner, model.Word, kind, i, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// appendFields adds the list fields of model after the ones word already has.
+func (repo *WordsRepo) appendFields(ctx context.Context, tx *sql.Tx, word string, model WordsModel) error {
+	fields := map[string][]string{
+		fieldSynonym: model.Synonyms,
+		fieldAntonym: model.Antonyms,
+		fieldExample: model.Examples,
+		fieldTag:     model.Tags,
+	}
+	for kind, values := range fields {
+		for _, value := range values {
+			if _, err := tx.ExecContext(ctx, `
+INSERT INTO word_fields (owner, word, kind, position, value)
+SELECT $1, $2, $3, COALESCE(MAX(position) + 1, 0), $4 FROM word_fields WHERE owner = $1 AND word = $2 AND kind = $3`,
+				model.Owner, word, kind, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes word of owner but keeps its cards and reviews, so adding it
+// again brings its history back. Cards of deleted words are never asked.
+func (repo *WordsRepo) Delete(ctx context.Context, owner int64, word string) error {
+	res, err := repo.db.ExecContext(ctx, "UPDATE words SET deleted_at = $1 WHERE owner = $2 AND word = $3 AND deleted_at IS NULL",
+		time.Now().In(time.UTC), owner, word)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Purge removes a deleted word of owner with its cards and reviews for good.
+func (repo *WordsRepo) Purge(ctx context.Context, owner int64, word string) error {
+	tx, err := repo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM words WHERE word = $1 AND owner = $2 AND deleted_at IS NOT NULL", word, owner)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	for _, table := range []string{"word_fields", "senses", "deck_words", "user_words", "reviews"} {
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE word = $1 AND owner = $2", table), word, owner); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SourceChatTx returns the chat word of owner was posted in as part of tx,
+// zero if it is from before posts were recorded.
+func (repo *WordsRepo) SourceChatTx(ctx context.Context, tx *sql.Tx, owner int64, word string) (int64, error) {
+	var chatID int64
+	err := tx.QueryRowContext(ctx, "SELECT source_chat_id FROM words WHERE owner = $1 AND word = $2", owner, word).Scan(&chatID)
+	return chatID, err
+}
+
+// GetBySource returns the words added with a post.
+func (repo *WordsRepo) GetBySource(ctx context.Context, chatID int64, messageID int) ([]WordsModel, error) {
+	rows, err := repo.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM words WHERE source_chat_id = $1 AND source_message_id = $2 AND deleted_at IS NULL", wordColumns),
+		chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []WordsModel
+	for rows.Next() {
+		res, err := scanWord(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *res)
+	}
+
+	return list, rows.Err()
 }
 
+// GetAllWords returns every shared word.
 func (repo *WordsRepo) GetAllWords(ctx context.Context) ([]WordsModel, error) {
-	rows, err := repo.db.QueryContext(ctx, "SELECT * FROM words")
+	rows, err := repo.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM words WHERE owner = 0 AND deleted_at IS NULL", wordColumns))
 	if err != nil {
 		return nil, err
 	}
@@ -55,22 +632,207 @@ func (repo *WordsRepo) GetAllWords(ctx context.Context) ([]WordsModel, error) {
 
 	var list []WordsModel
 	for rows.Next() {
-		var res WordsModel
-		if err = rows.Scan(&res.Word, &res.Meaning, &res.FileID, &res.CreatedAt); err != nil {
+		res, err := scanWord(rows)
+		if err != nil {
 			return nil, err
 		}
-		list = append(list, res)
+		list = append(list, *res)
 	}
 
 	return list, nil
 }
 
-func (repo *WordsRepo) GetByWords(ctx context.Context, word string) (*WordsModel, error) {
-	var res WordsModel
-	if err := repo.db.QueryRowContext(ctx, "SELECT * FROM words WHERE word = $1", word).
-		Scan(&res.Word, &res.Meaning, &res.FileID, &res.CreatedAt); err != nil {
+// GetByWords returns word as userID sees it: their own word if they have one
+// by that name, the shared one otherwise.
+func (repo *WordsRepo) GetByWords(ctx context.Context, userID int64, word string) (*WordsModel, error) {
+	res, err := scanWord(repo.db.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT %s FROM words WHERE word = $1 AND owner IN (0, $2) AND deleted_at IS NULL
+ORDER BY owner DESC LIMIT 1`, wordColumns), word, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Senses, err = getSenses(ctx, repo.db, res.Owner, word); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.QueryContext(ctx, "SELECT kind, value FROM word_fields WHERE owner = $1 AND word = $2 ORDER BY kind, position",
+		res.Owner, word)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind, value string
+		if err = rows.Scan(&kind, &value); err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case fieldSynonym:
+			res.Synonyms = append(res.Synonyms, value)
+		case fieldAntonym:
+			res.Antonyms = append(res.Antonyms, value)
+		case fieldExample:
+			res.Examples = append(res.Examples, value)
+		case fieldTag:
+			res.Tags = append(res.Tags, value)
+		}
+	}
+
+	return res, rows.Err()
+}
+
+// GetLanguages returns the source language of each of words as userID sees
+// them, see GetByWords. Words that can't be found are left out.
+func (repo *WordsRepo) GetLanguages(ctx context.Context, userID int64, words []string) (map[string]string, error) {
+	res := make(map[string]string, len(words))
+	if len(words) == 0 {
+		return res, nil
+	}
+
+	placeholders := make([]string, 0, len(words))
+	args := []interface{}{userID}
+	for _, word := range words {
+		placeholders = append(placeholders, "?")
+		args = append(args, word)
+	}
+
+	// a user's own word comes last and hides a shared one of the same name.
+	rows, err := repo.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT word, source_lang FROM words WHERE owner IN (0, ?) AND deleted_at IS NULL AND word IN (%s)
+ORDER BY owner = 0 DESC`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var word, source string
+		if err = rows.Scan(&word, &source); err != nil {
+			return nil, err
+		}
+		res[word] = source
+	}
+
+	return res, rows.Err()
+}
+
+// GetDistractors returns up to n words userID can see other than word, to be
+// used as wrong options for it in a quiz. Words with the same part of speech
+// come first, so the answer doesn't stand out, then ones with a meaning of
+// similar length.
+func (repo *WordsRepo) GetDistractors(ctx context.Context, userID int64, word WordsModel, n int) ([]WordsModel, error) {
+	rows, err := repo.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT %s FROM words WHERE word != $1 AND meaning != $2 AND owner IN (0, $3) AND deleted_at IS NULL
+ORDER BY $4 != '' AND pos != $4, ABS(LENGTH(meaning) - LENGTH($2)), RANDOM() LIMIT $5`, wordColumns),
+		word.Word, word.Meaning, userID, word.PartOfSpeech, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []WordsModel
+	for rows.Next() {
+		res, err := scanWord(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *res)
+	}
+
+	return list, rows.Err()
+}
+
+// GetDeleted returns word as userID sees it, see GetByWords, if it has been
+// deleted.
+func (repo *WordsRepo) GetDeleted(ctx context.Context, userID int64, word string) (*WordsModel, error) {
+	return scanWord(repo.db.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT %s FROM words WHERE word = $1 AND owner IN (0, $2) AND deleted_at IS NOT NULL
+ORDER BY owner DESC LIMIT 1`, wordColumns), word, userID))
+}
+
+// GetByName is GetByWords for the word someone typed as name, in case it
+// was typed differently than it is stored. name is matched by its key in the
+// language of each word, see lang.Key, trying language first, and only if no
+// word has that key by its folded key, see lang.FoldedKey.
+func (repo *WordsRepo) GetByName(ctx context.Context, userID int64, language, name string) (*WordsModel, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+SELECT DISTINCT source_lang FROM words WHERE owner IN (0, $1) AND deleted_at IS NULL AND source_lang != $2`, userID, language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	languages := []string{language}
+	for rows.Next() {
+		var l string
+		if err = rows.Scan(&l); err != nil {
+			return nil, err
+		}
+		languages = append(languages, l)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := []struct {
+		column string
+		key    func(language, word string) string
+	}{
+		{"word_key", wordKey},
+		{"folded_key", foldedKey},
+	}
+	for _, k := range keys {
+		for _, l := range languages {
+			var word string
+			err = repo.db.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT word FROM words WHERE %s = $1 AND source_lang = $2 AND owner IN (0, $3) AND deleted_at IS NULL
+ORDER BY owner DESC, word LIMIT 1`, k.column), k.key(l, name), l, userID).Scan(&word)
+			if err == nil {
+				return repo.GetByWords(ctx, userID, word)
+			} else if err != sql.ErrNoRows {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+// KeyWordTx returns the word of owner with key as part of tx, deleted or not,
+// so a new word typed differently than an existing one is taken for it. It
+// returns sql.ErrNoRows if there is none.
+func (repo *WordsRepo) KeyWordTx(ctx context.Context, tx *sql.Tx, owner int64, key string) (string, error) {
+	var word string
+	err := tx.QueryRowContext(ctx, `
+SELECT word FROM words WHERE owner = $1 AND word_key = $2
+ORDER BY deleted_at IS NOT NULL, word LIMIT 1`, owner, key).Scan(&word)
+	return word, err
+}
+
+// wordKey is the key of word, whose language is the BCP-47 tag language.
+func wordKey(language, word string) string {
+	return lang.Key(lang.Of(language), word)
+}
+
+// foldedKey is wordKey case folded, see lang.FoldedKey.
+func foldedKey(language, word string) string {
+	return lang.FoldedKey(lang.Of(language), word)
+}
+
+func scanWord(row interface{ Scan(...any) error }) (*WordsModel, error) {
+	var (
+		res       WordsModel
+		deletedAt sql.NullTime
+	)
+	if err := row.Scan(&res.Word, &res.Meaning, &res.FileID, &res.CreatedAt, &res.PartOfSpeech, &res.IPA,
+		&res.SourceChatID, &res.SourceMessageID, &deletedAt, &res.Owner,
+		&res.SourceLang, &res.TargetLang, &res.SourceChatTitle); err != nil {
 		return nil, err
 	}
+	res.DeletedAt = deletedAt.Time
 
 	return &res, nil
 }
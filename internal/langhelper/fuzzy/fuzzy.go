@@ -0,0 +1,101 @@
+package fuzzy
+
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	// Insert is a character the answer is missing.
+	Insert
+	// Delete is a character the answer should not have.
+	Delete
+)
+
+// Op is one step of the character level diff between an answer and the
+// expected text. Consecutive characters of the same kind are grouped.
+type Op struct {
+	Kind OpKind
+	Text string
+}
+
+// Distance returns the Damerau-Levenshtein (optimal string alignment) distance
+// between a and b, counted in runes.
+func Distance(a, b string) int {
+	d := matrix([]rune(a), []rune(b))
+	return d[len(d)-1][len(d[0])-1]
+}
+
+// Diff returns the operations turning answer into expected. A transposition is
+// reported as a delete and an insert.
+func Diff(answer, expected string) []Op {
+	a, b := []rune(answer), []rune(expected)
+	d := matrix(a, b)
+
+	var ops []Op
+	push := func(kind OpKind, r rune) {
+		if len(ops) > 0 && ops[len(ops)-1].Kind == kind {
+			ops[len(ops)-1].Text = string(r) + ops[len(ops)-1].Text
+			return
+		}
+		ops = append(ops, Op{Kind: kind, Text: string(r)})
+	}
+
+	// walk back from the bottom right corner, prepending operations.
+	i, j := len(a), len(b)
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && a[i-1] == b[j-1] && d[i][j] == d[i-1][j-1]:
+			push(Equal, a[i-1])
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+1:
+			push(Insert, b[j-1])
+			push(Delete, a[i-1])
+			i, j = i-1, j-1
+		case i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i][j] == d[i-2][j-2]+1:
+			push(Insert, b[j-1])
+			push(Insert, b[j-2])
+			push(Delete, a[i-1])
+			push(Delete, a[i-2])
+			i, j = i-2, j-2
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			push(Delete, a[i-1])
+			i--
+		default:
+			push(Insert, b[j-1])
+			j--
+		}
+	}
+
+	// ops were built backwards.
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+
+	return ops
+}
+
+func matrix(a, b []rune) [][]int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d
+}
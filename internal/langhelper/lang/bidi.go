@@ -0,0 +1,73 @@
+package lang
+
+import (
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// isolation marks, see https://unicode.org/reports/tr9/#Explicit_Directional_Isolates
+const (
+	leftToRightIsolate = "\u2066"
+	rightToLeftIsolate = "\u2067"
+	popIsolate         = "\u2069"
+)
+
+// rightToLeftScripts are the scripts written from right to left that a
+// language tag may have.
+var rightToLeftScripts = map[string]bool{
+	"Adlm": true,
+	"Arab": true,
+	"Hebr": true,
+	"Mand": true,
+	"Nkoo": true,
+	"Rohg": true,
+	"Samr": true,
+	"Syrc": true,
+	"Thaa": true,
+}
+
+// Direction is the direction of s as the bidi algorithm sees a paragraph of
+// it: the one of its first letter, bidi.Neutral if it has none.
+func Direction(s string) bidi.Direction {
+	for _, r := range s {
+		props, _ := bidi.LookupRune(r)
+		switch props.Class() {
+		case bidi.L:
+			return bidi.LeftToRight
+		case bidi.R, bidi.AL:
+			return bidi.RightToLeft
+		}
+	}
+
+	return bidi.Neutral
+}
+
+// IsRightToLeft reports whether the language of tag is written from right to
+// left, like Persian.
+func IsRightToLeft(tag language.Tag) bool {
+	script, _ := tag.Script()
+	return rightToLeftScripts[script.String()]
+}
+
+// Isolate wraps s, a text in the language of tag, in isolation marks so it is
+// laid out in its own direction whatever is around it. Telegram picks one
+// direction for a whole message by its first letter, so without them a
+// Persian meaning in an English card has its numbers and punctuation on the
+// wrong side. The direction is the one of the language if it is written from
+// right to left, otherwise the one of s, and text without letters is left
+// alone.
+func Isolate(tag language.Tag, s string) string {
+	direction := Direction(s)
+	if IsRightToLeft(tag) && direction != bidi.Neutral {
+		direction = bidi.RightToLeft
+	}
+
+	switch direction {
+	case bidi.LeftToRight:
+		return leftToRightIsolate + s + popIsolate
+	case bidi.RightToLeft:
+		return rightToLeftIsolate + s + popIsolate
+	}
+
+	return s
+}
@@ -0,0 +1,66 @@
+package lang
+
+import (
+	"golang.org/x/text/unicode/bidi"
+	"testing"
+)
+
+func TestDirection(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bidi.Direction
+	}{
+		{"apple", bidi.LeftToRight},
+		{"سیب", bidi.RightToLeft},
+		{"שלום", bidi.RightToLeft},
+		{"1. سیب", bidi.RightToLeft},
+		{"(apple) سیب", bidi.LeftToRight},
+		{"123 ...", bidi.Neutral},
+		{"", bidi.Neutral},
+	}
+	for _, tt := range tests {
+		if got := Direction(tt.s); got != tt.want {
+			t.Errorf("Direction(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestIsRightToLeft(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"fa", true},
+		{"ar", true},
+		{"he", true},
+		{"en", false},
+		{"de", false},
+		{"az-Arab", true},
+		{"az", false},
+	}
+	for _, tt := range tests {
+		if got := IsRightToLeft(Of(tt.tag)); got != tt.want {
+			t.Errorf("IsRightToLeft(%s) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestIsolate(t *testing.T) {
+	tests := []struct {
+		tag, s, want string
+	}{
+		{"en", "apple", leftToRightIsolate + "apple" + popIsolate},
+		{"fa", "سیب", rightToLeftIsolate + "سیب" + popIsolate},
+		// a Persian meaning that starts with a Latin word is still laid out
+		// from right to left.
+		{"fa", "CPU واحد پردازش", rightToLeftIsolate + "CPU واحد پردازش" + popIsolate},
+		{"en", "سیب", rightToLeftIsolate + "سیب" + popIsolate},
+		{"fa", "123", "123"},
+		{"en", "", ""},
+	}
+	for _, tt := range tests {
+		if got := Isolate(Of(tt.tag), tt.s); got != tt.want {
+			t.Errorf("Isolate(%s, %q) = %q, want %q", tt.tag, tt.s, got, tt.want)
+		}
+	}
+}
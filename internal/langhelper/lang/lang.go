@@ -0,0 +1,152 @@
+// Package lang handles words by the BCP-47 tag of their language: how they
+// are lowercased to be stored, capitalized to be shown, folded to be compared
+// and sorted.
+package lang
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// Default is the language of words whose language isn't known, which is the
+// one the bot was written for.
+var Default = language.English
+
+// FoldDiacritics makes Key drop diacritics, so "café" and "cafe" are the same
+// word. It is set once at startup, before any word is stored.
+var FoldDiacritics = false
+
+// Parse parses a BCP-47 tag like "de", "tr" or "fa-IR".
+func Parse(s string) (language.Tag, error) {
+	return language.Parse(strings.TrimSpace(s))
+}
+
+// Of returns the tag s names, Default if it is empty or invalid.
+func Of(s string) language.Tag {
+	if s == "" {
+		return Default
+	}
+
+	tag, err := Parse(s)
+	if err != nil {
+		return Default
+	}
+
+	return tag
+}
+
+// quotes are the typographic quotes phones like to put around a word.
+const quotes = "‘’‚‛“”„‟«»"
+
+// endings are the punctuation a word typed as a sentence ends with.
+const endings = ".,!?;:"
+
+// diacritics drops the combining marks of decomposed text.
+var diacritics = runes.Remove(runes.In(unicode.Mn))
+
+// persian maps the Arabic letters Persian is often typed with to the Persian
+// ones, which look the same but aren't equal.
+var persian = strings.NewReplacer("ي", "ی", "ى", "ی", "ك", "ک")
+
+// Normalize NFC normalizes s and spells the letters of its language one way
+// however they were typed.
+func Normalize(tag language.Tag, s string) string {
+	s = norm.NFC.String(s)
+	if base, _ := tag.Base(); base.String() == "fa" {
+		s = persian.Replace(s)
+	}
+
+	return s
+}
+
+// Clean removes what is typed around a word without being part of it: the
+// typographic quotes and the punctuation a sentence ends with, so "“Apple.”"
+// is "Apple". Anything else is kept, "-ish" and "'tis" are words too.
+func Clean(word string) string {
+	word = strings.Trim(strings.TrimSpace(word), quotes)
+	return strings.TrimSpace(strings.TrimRight(word, endings+quotes))
+}
+
+// Key is what tells words apart: two words with the same key are the same
+// word however they were typed. It is word normalized, cleaned and case
+// folded the way its language does it, see Clean and Fold, and without
+// diacritics if FoldDiacritics is set. Languages where case tells words
+// apart, like German nouns, keep it, see FoldedKey.
+func Key(tag language.Tag, word string) string {
+	word = Clean(Normalize(tag, word))
+	if !keepsCase(tag) {
+		word = Fold(tag, word)
+	}
+	if FoldDiacritics {
+		word, _, _ = transform.String(transform.Chain(norm.NFD, diacritics, norm.NFC), word)
+	}
+
+	return word
+}
+
+// FoldedKey is Key case folded in every language, so "haus" finds "Haus" when
+// there is no word "haus".
+func FoldedKey(tag language.Tag, word string) string {
+	return Fold(tag, Key(tag, word))
+}
+
+// Lower is the form of word that is stored: normalized and lowercased the way
+// its language does it, so a Turkish "I" becomes "ı". Languages where case
+// tells words apart, like German nouns, keep it.
+func Lower(tag language.Tag, word string) string {
+	word = Normalize(tag, strings.TrimSpace(word))
+	if keepsCase(tag) {
+		return word
+	}
+
+	return cases.Lower(tag).String(word)
+}
+
+// Title capitalizes a stored word to be shown. Words of languages that keep
+// their case are shown as they are.
+func Title(tag language.Tag, word string) string {
+	if keepsCase(tag) {
+		return word
+	}
+
+	return cases.Title(tag).String(word)
+}
+
+// Fold prepares s to be compared with another string of the same language
+// regardless of case: it is normalized, lowercased the way the language does
+// it, so a Turkish "I" matches "ı", and case folded.
+func Fold(tag language.Tag, s string) string {
+	return cases.Fold().String(cases.Lower(tag).String(Normalize(tag, strings.TrimSpace(s))))
+}
+
+// Sort sorts words in the alphabetical order of their language.
+func Sort(tag language.Tag, words []string) {
+	collate.New(tag, collate.IgnoreCase).SortStrings(words)
+}
+
+// Less returns a less function for sort.Slice that orders strings the way
+// Sort does.
+func Less(tag language.Tag) func(a, b string) bool {
+	c := collate.New(tag, collate.IgnoreCase)
+	return func(a, b string) bool {
+		return c.CompareString(a, b) < 0
+	}
+}
+
+// keepsCase reports whether case is part of how words of the language are
+// spelled, so lowercasing them would be wrong.
+func keepsCase(tag language.Tag) bool {
+	base, _ := tag.Base()
+	switch base.String() {
+	case "de", "lb":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package lang
+
+import "testing"
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		tag, a, b string
+	}{
+		{"en", "Apple.", "“apple”"},
+		{"en", "apple!?", "«Apple»"},
+		{"en", "cafe\u0301", "café"},
+		{"de", "Haus", "„Haus“"},
+		{"tr", "IRMAK", "ırmak"},
+		{"fa", "كتاب", "کتاب"},
+	}
+	for _, tt := range tests {
+		if a, b := Key(Of(tt.tag), tt.a), Key(Of(tt.tag), tt.b); a != b {
+			t.Errorf("%s: %q and %q have keys %q and %q", tt.tag, tt.a, tt.b, a, b)
+		}
+	}
+}
+
+func TestKeyTellsWordsApart(t *testing.T) {
+	tests := []struct {
+		tag, a, b string
+	}{
+		{"en", "-ish", "ish"},
+		{"en", "'tis", "tis"},
+		{"en", "e.g.", "eg"},
+		{"de", "Essen", "essen"},
+	}
+	for _, tt := range tests {
+		if a, b := Key(Of(tt.tag), tt.a), Key(Of(tt.tag), tt.b); a == b {
+			t.Errorf("%s: %q and %q have the same key %q", tt.tag, tt.a, tt.b, a)
+		}
+	}
+}
+
+func TestFoldedKey(t *testing.T) {
+	if a, b := FoldedKey(Of("de"), "Essen"), FoldedKey(Of("de"), "essen."); a != b {
+		t.Errorf("got folded keys %q and %q, want the same", a, b)
+	}
+}
+
+func TestLower(t *testing.T) {
+	tests := []struct {
+		tag, word, want string
+	}{
+		{"en", " Apple. ", "apple."},
+		{"en", "Cafe\u0301", "café"},
+		{"de", " Haus ", "Haus"},
+		{"tr", "IRMAK", "ırmak"},
+	}
+	for _, tt := range tests {
+		if got := Lower(Of(tt.tag), tt.word); got != tt.want {
+			t.Errorf("Lower(%s, %q) = %q, want %q", tt.tag, tt.word, got, tt.want)
+		}
+	}
+}
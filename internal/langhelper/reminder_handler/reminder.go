@@ -0,0 +1,174 @@
+package reminder_handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/itzloop/langhelperbot/internal/tgapi"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// ClockLayout is the format reminder and quiet hour times are stored in.
+const ClockLayout = "15:04"
+
+// ReminderHandler sends each user a card once a day at the time they have
+// chosen, if they have anything due.
+type ReminderHandler struct {
+	interval      time.Duration
+	updateFetcher *tgapi.UpdateFetcher
+	usersRepo     *db.UsersRepo
+	countDue      func(ctx context.Context, userID int64, at time.Time) (int, error)
+	sendCard      func(ctx context.Context, userID int64) error
+}
+
+// NewReminderHandler creates a ReminderHandler checking for reminders every
+// interval. countDue is called to count the cards the user has due and
+// sendCard to show the user a card.
+func NewReminderHandler(interval time.Duration, updateFetcher *tgapi.UpdateFetcher, usersRepo *db.UsersRepo,
+	countDue func(ctx context.Context, userID int64, at time.Time) (int, error),
+	sendCard func(ctx context.Context, userID int64) error) *ReminderHandler {
+	return &ReminderHandler{
+		interval:      interval,
+		updateFetcher: updateFetcher,
+		usersRepo:     usersRepo,
+		countDue:      countDue,
+		sendCard:      sendCard,
+	}
+}
+
+func (rh *ReminderHandler) Start(ctx context.Context) (err error) {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":     "ReminderHandler.Start",
+		"interval": rh.interval,
+	})
+
+	entry.Info("running reminder handler")
+	if err = rh.updateFetcher.BlockTillStarted(ctx); err != nil {
+		entry.WithError(err).Error("couldn't wait for UpdateFetcher to start")
+		return err
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("ReminderHandler recovered: %v", e)
+		}
+	}()
+
+	ticker := time.NewTicker(rh.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() != context.Canceled {
+				return ctx.Err()
+			}
+
+			return nil
+		case now := <-ticker.C:
+			rh.remind(ctx, entry, now.In(time.UTC))
+		}
+	}
+}
+
+func (rh *ReminderHandler) remind(ctx context.Context, entry *logrus.Entry, now time.Time) {
+	reminders, err := rh.usersRepo.ListReminders(ctx)
+	if err != nil {
+		entry.WithError(err).Error("failed to list reminders")
+		return
+	}
+
+	for _, reminder := range reminders {
+		e := entry.WithField("user_id", reminder.UserID)
+		if !isDue(reminder, now) {
+			continue
+		}
+
+		if err = rh.remindUser(ctx, reminder.UserID, now); err != nil {
+			var tgErr *tgbotapi.Error
+			if errors.As(err, &tgErr) && tgErr.Code == http.StatusForbidden {
+				e.Info("user has blocked the bot")
+				if err = rh.usersRepo.SetBlocked(ctx, reminder.UserID, true); err != nil {
+					e.WithError(err).Error("failed to mark user as blocked")
+				}
+				continue
+			}
+
+			// try again on the next tick.
+			e.WithError(err).Error("failed to remind user")
+			continue
+		}
+
+		if err = rh.usersRepo.SetLastReminded(ctx, reminder.UserID, now); err != nil {
+			e.WithError(err).Error("failed to set last reminded")
+		}
+	}
+}
+
+func (rh *ReminderHandler) remindUser(ctx context.Context, userID int64, now time.Time) error {
+	due, err := rh.countDue(ctx, userID, now)
+	if err != nil || due == 0 {
+		return err
+	}
+
+	if _, err = rh.updateFetcher.GetBot().Send(tgbotapi.NewMessage(userID,
+		fmt.Sprintf("Time to review! You have %d due words.", due))); err != nil {
+		return err
+	}
+
+	return rh.sendCard(ctx, userID)
+}
+
+// isDue reports whether the user should be reminded at now: their reminder
+// time has passed today, they haven't been reminded since, and it is not quiet
+// hours for them.
+func isDue(reminder db.ReminderModel, now time.Time) bool {
+	loc, err := time.LoadLocation(reminder.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	remindAt, err := time.Parse(ClockLayout, reminder.RemindAt)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), remindAt.Hour(), remindAt.Minute(), 0, 0, loc)
+	if local.Before(today) || !reminder.LastReminded.Before(today) {
+		return false
+	}
+
+	return !InQuietHours(reminder.QuietStart, reminder.QuietEnd, local)
+}
+
+// InQuietHours reports whether the clock time of local falls between start and
+// end, which may wrap around midnight. Empty or equal bounds mean no quiet hours.
+func InQuietHours(start, end string, local time.Time) bool {
+	s, err := time.Parse(ClockLayout, start)
+	if err != nil {
+		return false
+	}
+
+	e, err := time.Parse(ClockLayout, end)
+	if err != nil {
+		return false
+	}
+
+	var (
+		from = s.Hour()*60 + s.Minute()
+		to   = e.Hour()*60 + e.Minute()
+		m    = local.Hour()*60 + local.Minute()
+	)
+	switch {
+	case from < to:
+		return from <= m && m < to
+	case from > to:
+		return m >= from || m < to
+	default:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package update_handlers
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// senderOf returns who sent msg, which came with update: the user, or the
+// chat itself for channel posts and for admins posting anonymously on behalf
+// of a group. The message of a button press is the bot's, so it is the user
+// who pressed it.
+func senderOf(update tgbotapi.Update, msg *tgbotapi.Message) int64 {
+	if update.CallbackQuery != nil && update.CallbackQuery.From != nil {
+		return update.CallbackQuery.From.ID
+	}
+
+	if msg.SenderChat != nil {
+		return msg.SenderChat.ID
+	}
+
+	if msg.From != nil {
+		return msg.From.ID
+	}
+
+	return msg.Chat.ID
+}
+
+// isAdmin reports whether senderID, see senderOf, may change the settings and
+// the words of chatID. Anyone can in their private chat, and so can a chat
+// posting as itself, otherwise it takes an admin of the chat.
+func (uh *UpdateHandler) isAdmin(chatID, senderID int64) (bool, error) {
+	if senderID == chatID {
+		return true, nil
+	}
+
+	member, err := uh.updateFetcher.GetBot().GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: senderID},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return member.IsCreator() || member.IsAdministrator(), nil
+}
+
+// requireAdmin is isAdmin that tells senderID when they are not one. Handlers
+// stop when it returns false, with its error if any.
+func (uh *UpdateHandler) requireAdmin(chatID, senderID int64) (bool, error) {
+	admin, err := uh.isAdmin(chatID, senderID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"spot":      "UpdateHandler.requireAdmin",
+			"chat_id":   chatID,
+			"sender_id": senderID,
+		}).WithError(err).Error("failed to check admin")
+		return false, err
+	}
+
+	if !admin {
+		return false, uh.sendText(chatID, "Only admins of this chat can do that.")
+	}
+
+	return true, nil
+}
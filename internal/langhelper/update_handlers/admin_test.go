@@ -0,0 +1,60 @@
+package update_handlers
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"testing"
+)
+
+func TestSenderOf(t *testing.T) {
+	const (
+		group = -100
+		user  = 7
+	)
+
+	tests := []struct {
+		name   string
+		update tgbotapi.Update
+		msg    *tgbotapi.Message
+		want   int64
+	}{
+		{
+			name: "member",
+			msg:  &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: group}, From: &tgbotapi.User{ID: user}},
+			want: user,
+		},
+		{
+			name: "anonymous admin",
+			msg: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: group}, From: &tgbotapi.User{ID: 1087968824},
+				SenderChat: &tgbotapi.Chat{ID: group}},
+			want: group,
+		},
+		{
+			name: "channel post",
+			msg:  &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: group}, SenderChat: &tgbotapi.Chat{ID: group}},
+			want: group,
+		},
+		{
+			name:   "button",
+			update: tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: &tgbotapi.User{ID: user}}},
+			msg:    &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: group}, From: &tgbotapi.User{ID: 1}},
+			want:   user,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := senderOf(test.update, test.msg); got != test.want {
+				t.Fatalf("senderOf() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsAdminOfOwnChat(t *testing.T) {
+	uh := newTestHandler(t)
+	for _, chatID := range []int64{7, -100} {
+		if admin, err := uh.isAdmin(chatID, chatID); err != nil || !admin {
+			t.Fatalf("isAdmin(%d, %d) = %v, %v, want true", chatID, chatID, admin, err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package update_handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// shortRefPrefix starts a short ref to a card, see buttonRef.
+	shortRefPrefix = "@"
+	// maxShortRefs is how many short refs a chat keeps. The buttons of older
+	// cards stop working.
+	maxShortRefs = 100
+
+	expiredCardText = "This card is too old, use " + RandomCommand + " to get a new one."
+)
+
+// buttonRef is ref, what the buttons under a card send to refer to it, if
+// "<command> <ref>" fits in callback data. Otherwise ref is kept in the state
+// of chatID and it is a short ref to it, "@<number>", see resolveRef. Refs
+// that look like short refs are always kept.
+func (uh *UpdateHandler) buttonRef(chatID int64, command, ref string) string {
+	if len(command)+1+len(ref) <= maxCallbackDataLen && !strings.HasPrefix(ref, shortRefPrefix) {
+		return ref
+	}
+
+	return fmt.Sprintf("%s%d", shortRefPrefix, uh.states.addRef(chatID, ref))
+}
+
+// callbackData is "<command> <ref>" for a button under a card of chatID, with
+// ref shortened by buttonRef.
+func (uh *UpdateHandler) callbackData(chatID int64, command, ref string) string {
+	return fmt.Sprintf("%s %s", command, uh.buttonRef(chatID, command, ref))
+}
+
+// resolveRef returns the ref a short ref of chatID stands for, or ref itself
+// if it is not a short ref. It returns false if the short ref is no longer
+// kept, because the bot restarted or too many came after it.
+func (uh *UpdateHandler) resolveRef(chatID int64, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if !strings.HasPrefix(ref, shortRefPrefix) {
+		return ref, true
+	}
+
+	number, err := strconv.Atoi(strings.TrimPrefix(ref, shortRefPrefix))
+	if err != nil {
+		return ref, true
+	}
+
+	return uh.states.ref(chatID, number)
+}
@@ -0,0 +1,62 @@
+package update_handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestButtonRef(t *testing.T) {
+	const chatID = 1
+
+	uh := &UpdateHandler{states: newChatStates()}
+	tests := []struct {
+		name  string
+		ref   string
+		short bool
+	}{
+		{name: "fits", ref: "apple"},
+		{name: "too long", ref: strings.Repeat("a", maxCallbackDataLen), short: true},
+		{name: "looks like a short ref", ref: "@1", short: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := uh.callbackData(chatID, GradeReverseCommand+" again", test.ref)
+			if len(data) > maxCallbackDataLen {
+				t.Fatalf("callback data %q is %d bytes", data, len(data))
+			}
+
+			ref := strings.TrimPrefix(data, GradeReverseCommand+" again ")
+			if short := ref != test.ref; short != test.short {
+				t.Fatalf("callback data %q, want a short ref %v", data, test.short)
+			}
+
+			resolved, ok := uh.resolveRef(chatID, ref)
+			if !ok || resolved != test.ref {
+				t.Fatalf("resolveRef(%q) = %q, %v, want %q", ref, resolved, ok, test.ref)
+			}
+		})
+	}
+}
+
+func TestButtonRefExpires(t *testing.T) {
+	const chatID = 1
+
+	uh := &UpdateHandler{states: newChatStates()}
+	long := strings.Repeat("a", maxCallbackDataLen)
+	first := uh.buttonRef(chatID, MeaningCommand, long)
+	for i := 0; i < maxShortRefs; i++ {
+		uh.buttonRef(chatID, MeaningCommand, fmt.Sprintf("%s%d", long, i))
+	}
+
+	if _, ok := uh.resolveRef(chatID, first); ok {
+		t.Fatalf("resolveRef(%q) found a ref that should be gone", first)
+	}
+	if _, ok := uh.resolveRef(chatID+1, "@2"); ok {
+		t.Fatal("resolveRef found a ref of another chat")
+	}
+	if ref, ok := uh.resolveRef(chatID, "@home"); !ok || ref != "@home" {
+		t.Fatalf("resolveRef(%q) = %q, %v, want it unchanged", "@home", ref, ok)
+	}
+}
@@ -0,0 +1,247 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// a message can't have too many buttons, decks after this many only get a
+// line in the list.
+const maxDeckButtons = 20
+
+// HandleDecks lists the decks with a button under each to subscribe to it or
+// unsubscribe from it.
+func (uh *UpdateHandler) HandleDecks(ctx context.Context, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleDecks",
+		"user_id": userID,
+	})
+
+	decks, err := uh.decksRepo.List(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to list decks")
+		return err
+	}
+
+	if len(decks) == 0 {
+		return uh.sendText(userID, "There are no decks yet.")
+	}
+
+	tag, err := uh.chatLanguage(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get language")
+		return err
+	}
+	less := lang.Less(tag)
+	sort.SliceStable(decks, func(i, j int) bool { return less(decks[i].Name, decks[j].Name) })
+
+	var (
+		sb   strings.Builder
+		rows [][]tgbotapi.InlineKeyboardButton
+	)
+	sb.WriteString("Decks (✅ subscribed):\n")
+	for _, deck := range decks {
+		mark, label, command := "▫️", "Subscribe to "+deck.Name, SubscribeCommand
+		if deck.Subscribed {
+			mark, label, command = "✅", "Unsubscribe from "+deck.Name, UnsubscribeCommand
+		}
+
+		fmt.Fprintf(&sb, "\n%s %s, %d words", mark, deck.Name, deck.Words)
+		if deck.SourceLang != "" && deck.TargetLang != "" {
+			fmt.Fprintf(&sb, " (%s → %s)", deck.SourceLang, deck.TargetLang)
+		} else if deck.SourceLang != "" {
+			fmt.Fprintf(&sb, " (%s)", deck.SourceLang)
+		}
+		if len(deck.Chats) > 0 {
+			fmt.Fprintf(&sb, ", from %s", strings.Join(deck.Chats, ", "))
+		}
+		if deck.Reverse != nil {
+			fmt.Fprintf(&sb, ", reverse cards %s", onOff(*deck.Reverse))
+		}
+
+		data := fmt.Sprintf("%s %s", command, deck.Name)
+		if len(rows) < maxDeckButtons && len(data) <= maxCallbackDataLen {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(label, data)))
+		}
+	}
+	fmt.Fprintf(&sb, "\n\nUse %s <deck> and %s <deck>, or %s on|off|default <deck> for its reverse cards.",
+		SubscribeCommand, UnsubscribeCommand, ReverseCommand)
+
+	msg := tgbotapi.NewMessage(userID, sb.String())
+	if len(rows) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send decks")
+		return err
+	}
+
+	return nil
+}
+
+// HandleSubscribe handles "/subscribe <deck>". The user gets cards for the
+// words of the deck they didn't have yet.
+func (uh *UpdateHandler) HandleSubscribe(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleSubscribe",
+		"user_id": userID,
+	})
+
+	deck := strings.TrimSpace(strings.TrimPrefix(text, SubscribeCommand))
+	if deck == "" {
+		return uh.sendText(userID, fmt.Sprintf("Use %s <deck>, %s lists them.", SubscribeCommand, DecksCommand))
+	}
+
+	if _, err := uh.usersRepo.GetReverseCards(ctx, userID); err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get user")
+		return err
+	}
+
+	words, err := uh.decksRepo.Subscribe(ctx, userID, deck)
+	if err != nil {
+		entry.WithError(err).Error("failed to subscribe")
+		return err
+	}
+
+	if len(words) == 0 {
+		return uh.sendText(userID, fmt.Sprintf("You are subscribed to %s, it has no words yet.", deck))
+	}
+
+	if err = uh.userWordsRepo.InsertBulkSingleUser(ctx, userID, words); err != nil {
+		entry.WithError(err).Error("failed to insert bulk single user")
+		return err
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("You are subscribed to %s, its %d words are in your queue.", deck, len(words)))
+}
+
+// HandleUnsubscribe handles "/unsubscribe <deck>". The cards of the deck are
+// kept, so subscribing again picks up where the user left off.
+func (uh *UpdateHandler) HandleUnsubscribe(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleUnsubscribe",
+		"user_id": userID,
+	})
+
+	deck := strings.TrimSpace(strings.TrimPrefix(text, UnsubscribeCommand))
+	if deck == "" {
+		return uh.sendText(userID, fmt.Sprintf("Use %s <deck>, %s lists them.", UnsubscribeCommand, DecksCommand))
+	}
+
+	err := uh.decksRepo.Unsubscribe(ctx, userID, deck)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, fmt.Sprintf("You are not subscribed to %s.", deck))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to unsubscribe")
+		return err
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("You are unsubscribed from %s. Its words aren't asked unless another deck of yours has them.", deck))
+}
+
+// HandleChatDeck handles "/chat_deck <deck>" which sets the deck words posted
+// in the chat go to from now on. Without a deck it shows the current one. In
+// groups only admins can set it, see isAdmin.
+func (uh *UpdateHandler) HandleChatDeck(ctx context.Context, text string, chatID, senderID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleChatDeck",
+		"chat_id": chatID,
+	})
+
+	deck := strings.TrimSpace(strings.TrimPrefix(text, ChatDeckCommand))
+	if deck == "" {
+		current, err := uh.chatsRepo.GetDeck(ctx, chatID)
+		if err != nil {
+			entry.WithError(err).Error("failed to get deck")
+			return err
+		}
+
+		return uh.sendText(chatID, fmt.Sprintf("Words posted here go to %s. Use %s <deck> to change it.\n\n"+
+			"Share https://t.me/%s?start=%d to subscribe people to it.",
+			current, ChatDeckCommand, uh.updateFetcher.GetBot().Self.UserName, chatID))
+	}
+
+	if ok, err := uh.requireAdmin(chatID, senderID); !ok {
+		return err
+	}
+
+	if err := uh.chatsRepo.SetDeck(ctx, chatID, deck); err != nil {
+		entry.WithError(err).Error("failed to set deck")
+		return err
+	}
+
+	return uh.sendText(chatID, fmt.Sprintf("Words posted here go to %s from now on.", deck))
+}
+
+// fileWordTx puts word in decks as part of tx, taking it out of the decks it
+// was in unless add is set, and gives everyone subscribed to its decks cards
+// for it. A private word is in no deck and only its owner gets cards.
+func (uh *UpdateHandler) fileWordTx(ctx context.Context, tx *sql.Tx, owner int64, word string, decks []string, add bool) error {
+	if owner != 0 {
+		return uh.userWordsRepo.InsertBulkSingleWordTx(ctx, tx, owner, word, []int64{owner})
+	}
+
+	var err error
+	if add {
+		err = uh.decksRepo.AddWordDecksTx(ctx, tx, word, decks)
+	} else {
+		err = uh.decksRepo.SetWordDecksTx(ctx, tx, word, decks)
+	}
+	if err != nil {
+		return err
+	}
+
+	users, err := uh.decksRepo.SubscribersTx(ctx, tx, word)
+	if err != nil {
+		return err
+	}
+
+	return uh.userWordsRepo.InsertBulkSingleWordTx(ctx, tx, 0, word, users)
+}
+
+// wordDecks are the decks of a word posted in a chat with deck: that one and
+// one for each of its tags.
+func wordDecks(deck string, tags []string) []string {
+	return append([]string{deck}, tags...)
+}
+
+// reverseOfDeck handles "/reverse on|off|default <deck>", which overrides the
+// reverse cards setting of the user for the words of deck.
+func (uh *UpdateHandler) reverseOfDeck(ctx context.Context, userID int64, setting, deck string) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.reverseOfDeck",
+		"user_id": userID,
+	})
+
+	var enabled *bool
+	switch setting {
+	case "on", "off":
+		b := setting == "on"
+		enabled = &b
+	case "default":
+	default:
+		return uh.sendText(userID, fmt.Sprintf("Use %s on|off|default <deck>.", ReverseCommand))
+	}
+
+	err := uh.decksRepo.SetReverseCards(ctx, userID, deck, enabled)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, fmt.Sprintf("You are not subscribed to %s.", deck))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to set reverse cards of deck")
+		return err
+	}
+
+	if enabled == nil {
+		return uh.sendText(userID, fmt.Sprintf("Reverse cards of %s follow your own setting.", deck))
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("Reverse cards of %s are %s.", deck, onOff(*enabled)))
+}
@@ -0,0 +1,52 @@
+package update_handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// HandleDuplicates handles "/duplicates <policy>" which sets what happens when
+// a word that already exists is posted in the chat. Without a policy it shows
+// the current one. In groups only admins can set it, see isAdmin.
+func (uh *UpdateHandler) HandleDuplicates(ctx context.Context, text string, chatID, senderID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleDuplicates",
+		"chat_id": chatID,
+	})
+
+	names := make([]string, 0, len(db.DuplicatePolicies))
+	for _, policy := range db.DuplicatePolicies {
+		names = append(names, string(policy))
+	}
+	usage := fmt.Sprintf("Use %s %s.", DuplicatesCommand, strings.Join(names, "|"))
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		policy, err := uh.chatsRepo.GetDuplicatePolicy(ctx, chatID)
+		if err != nil {
+			entry.WithError(err).Error("failed to get duplicate policy")
+			return err
+		}
+
+		return uh.sendText(chatID, fmt.Sprintf("Words posted twice are handled with %s.\n%s", policy, usage))
+	}
+
+	if ok, err := uh.requireAdmin(chatID, senderID); !ok {
+		return err
+	}
+
+	policy, err := db.ParseDuplicatePolicy(strings.ToLower(fields[1]))
+	if err != nil {
+		return uh.sendText(chatID, usage)
+	}
+
+	if err = uh.chatsRepo.SetDuplicatePolicy(ctx, chatID, policy); err != nil {
+		entry.WithError(err).Error("failed to set duplicate policy")
+		return err
+	}
+
+	return uh.sendText(chatID, fmt.Sprintf("Words posted twice are handled with %s from now on.", policy))
+}
@@ -0,0 +1,239 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/caption"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/language"
+	"strings"
+)
+
+// HandleEdit handles "/edit <word>" followed by the new caption of the word
+// on the next lines. Without a caption it sends the current one to be edited.
+// In groups only admins can edit words, see isAdmin.
+func (uh *UpdateHandler) HandleEdit(ctx context.Context, text string, chatID, senderID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleEdit",
+		"chat_id": chatID,
+	})
+
+	first, rest, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	name := wordArgument(first)
+	if name == "" {
+		return uh.sendText(chatID, fmt.Sprintf("Use %s <word> with the new caption on the next lines.", EditCommand))
+	}
+
+	name, err := uh.wordName(ctx, chatID, name)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word name")
+		return err
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, chatID, name)
+	if err == sql.ErrNoRows {
+		return uh.sendText(chatID, fmt.Sprintf("There is no word %q.", name))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	if !canChange(word, chatID) {
+		return uh.sendText(chatID, fmt.Sprintf("%s was added in another chat, it can only be changed there.", name))
+	}
+
+	if ok, err := uh.requireAdmin(chatID, senderID); !ok {
+		return err
+	}
+
+	if strings.TrimSpace(rest) == "" {
+		return uh.sendText(chatID, fmt.Sprintf("Send this back with your changes:\n\n%s %s\n%s", EditCommand, word.Word, caption.Format(&caption.Caption{
+			Word:         word.Word,
+			Meaning:      word.Meaning,
+			PartOfSpeech: word.PartOfSpeech,
+			IPA:          word.IPA,
+			Synonyms:     word.Synonyms,
+			Antonyms:     word.Antonyms,
+			Examples:     word.Examples,
+			Tags:         word.Tags,
+			Senses:       captionSenses(word.Senses),
+			SourceLang:   word.SourceLang,
+			TargetLang:   word.TargetLang,
+		})))
+	}
+
+	post, err := caption.Parse(rest)
+	if err != nil {
+		return uh.sendText(chatID, fmt.Sprintf("Couldn't edit %s, %s", name, err))
+	}
+
+	// the word stays in the deck of the chat it was posted in.
+	deck, err := uh.chatsRepo.GetDeck(ctx, word.SourceChatID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get deck")
+		return err
+	}
+
+	tx, err := uh.wordsRepo.BeginTx(ctx)
+	if err != nil {
+		entry.WithError(err).Error("failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	model := wordModel(post, word.FileID, word.SourceChatID, word.SourceMessageID, word.SourceLang, word.TargetLang)
+	model.Owner = word.Owner
+	if lang.Key(lang.Of(model.SourceLang), model.Word) == "" {
+		return uh.sendText(chatID, fmt.Sprintf("Couldn't edit %s, %q is not a word.", name, post.Word))
+	}
+
+	err = uh.wordsRepo.UpdateTx(ctx, tx, word.Word, model)
+	if errors.Is(err, db.ErrDuplicate) {
+		return uh.sendText(chatID, fmt.Sprintf("Can't rename %s, %s already exists.", word.Word, model.Word))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to update word")
+		return err
+	}
+
+	if err = uh.fileWordTx(ctx, tx, word.Owner, model.Word, wordDecks(deck, post.Tags), false); err != nil {
+		entry.WithError(err).Error("failed to file word")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		entry.WithError(err).Error("failed to commit word")
+		return err
+	}
+
+	return uh.sendText(chatID, fmt.Sprintf("%s is updated.", model.Word))
+}
+
+func captionSenses(senses []db.SenseModel) []caption.Sense {
+	var list []caption.Sense
+	for _, sense := range senses {
+		list = append(list, caption.Sense{
+			Definition:   sense.Definition,
+			PartOfSpeech: sense.PartOfSpeech,
+			Example:      sense.Example,
+		})
+	}
+
+	return list
+}
+
+// HandleDelete handles "/delete <word>". The word is gone for everyone but
+// its cards and reviews are kept, so posting it again brings them back. A
+// button under the reply removes those too. In groups only admins can delete
+// words.
+func (uh *UpdateHandler) HandleDelete(ctx context.Context, text string, chatID, senderID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleDelete",
+		"chat_id": chatID,
+	})
+
+	name := wordArgument(text)
+	if name == "" {
+		return uh.sendText(chatID, fmt.Sprintf("Use %s <word>.", DeleteCommand))
+	}
+
+	name, err := uh.wordName(ctx, chatID, name)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word name")
+		return err
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, chatID, name)
+	if err == sql.ErrNoRows {
+		return uh.sendText(chatID, fmt.Sprintf("There is no word %q.", name))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	if !canChange(word, chatID) {
+		return uh.sendText(chatID, fmt.Sprintf("%s was added in another chat, it can only be deleted there.", name))
+	}
+
+	if ok, err := uh.requireAdmin(chatID, senderID); !ok {
+		return err
+	}
+
+	if err = uh.wordsRepo.Delete(ctx, word.Owner, word.Word); err != nil {
+		entry.WithError(err).Error("failed to delete word")
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%s is deleted. Its review history is kept, post it again to bring it back.", word.Word))
+	if data := fmt.Sprintf("%s %s", PurgeCommand, word.Word); len(data) <= maxCallbackDataLen {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Delete History Too", data),
+			),
+		)
+	}
+	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+}
+
+// HandlePurge handles "/purge <word>", which removes a deleted word with its
+// cards and review history. In groups only admins can purge words.
+func (uh *UpdateHandler) HandlePurge(ctx context.Context, text string, chatID, senderID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandlePurge",
+		"chat_id": chatID,
+	})
+
+	name := wordArgument(text)
+	word, err := uh.wordsRepo.GetDeleted(ctx, chatID, name)
+	if err == sql.ErrNoRows {
+		// the button sends the word as it is stored, someone typing it may
+		// not have.
+		var tag language.Tag
+		if tag, err = uh.chatLanguage(ctx, chatID); err != nil {
+			entry.WithError(err).Error("failed to get language")
+			return err
+		}
+		word, err = uh.wordsRepo.GetDeleted(ctx, chatID, lang.Lower(tag, name))
+	}
+	if err == sql.ErrNoRows {
+		return uh.sendText(chatID, fmt.Sprintf("%q has to be deleted with %s first.", name, DeleteCommand))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	if !canChange(word, chatID) {
+		return uh.sendText(chatID, fmt.Sprintf("%s was added in another chat, it can only be deleted there.", name))
+	}
+
+	if ok, err := uh.requireAdmin(chatID, senderID); !ok {
+		return err
+	}
+
+	if err = uh.wordsRepo.Purge(ctx, word.Owner, word.Word); err != nil {
+		entry.WithError(err).Error("failed to purge word")
+		return err
+	}
+
+	return uh.sendText(chatID, fmt.Sprintf("%s and its history are gone.", word.Word))
+}
+
+// canChange reports whether word may be edited or deleted from chatID, which
+// is only the chat it was posted in, or the private chat of its owner. Shared
+// words from before posts were recorded can be changed from anywhere.
+func canChange(word *db.WordsModel, chatID int64) bool {
+	if word.Owner != 0 {
+		return word.Owner == chatID
+	}
+
+	return word.SourceChatID == 0 || word.SourceChatID == chatID
+}
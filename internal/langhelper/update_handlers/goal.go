@@ -0,0 +1,144 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandleGoal sets the number of reviews the user wants to do every day with
+// "/goal 20" and removes it with "/goal off". Without an argument it shows
+// today's progress and the streak.
+func (uh *UpdateHandler) HandleGoal(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleGoal",
+		"user_id": userID,
+	})
+
+	streak, err := uh.usersRepo.GetStreak(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get streak")
+		return err
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		if streak.DailyGoal == 0 {
+			return uh.sendText(userID, fmt.Sprintf("You have no daily goal, set one with %s <reviews>.", GoalCommand))
+		}
+
+		progress, err := uh.goalProgress(ctx, streak)
+		if err != nil {
+			entry.WithError(err).Error("failed to get goal progress")
+			return err
+		}
+
+		return uh.sendText(userID, fmt.Sprintf("%s\nStreak freezes: %d", progress, streak.Freezes))
+	}
+
+	goal, err := strconv.Atoi(fields[1])
+	if strings.EqualFold(fields[1], "off") {
+		goal, err = 0, nil
+	}
+	if err != nil || goal < 0 {
+		return uh.sendText(userID, fmt.Sprintf("Invalid goal %q, use %s <reviews> like %s 20.", fields[1], GoalCommand, GoalCommand))
+	}
+
+	if err = uh.usersRepo.SetDailyGoal(ctx, userID, goal); err != nil {
+		entry.WithError(err).Error("failed to set daily goal")
+		return err
+	}
+
+	if goal == 0 {
+		return uh.sendText(userID, "Daily goal is off.")
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("Your daily goal is %d reviews.", goal))
+}
+
+// trackGoal is called after every answer. It extends the streak when the
+// daily goal is reached, celebrates milestones and returns a line showing the
+// progress, which is empty if the user has no goal.
+func (uh *UpdateHandler) trackGoal(ctx context.Context, userID int64) string {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.trackGoal",
+		"user_id": userID,
+	})
+
+	streak, err := uh.usersRepo.GetStreak(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get streak")
+		return ""
+	}
+
+	if streak.DailyGoal == 0 {
+		return ""
+	}
+
+	today, done, err := uh.reviewsToday(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to count reviews")
+		return ""
+	}
+
+	if done >= streak.DailyGoal && streak.LastDay != today {
+		milestone := streak.Meet(today)
+		if err = uh.usersRepo.UpdateStreak(ctx, streak); err != nil {
+			entry.WithError(err).Error("failed to update streak")
+			return ""
+		}
+
+		if milestone {
+			_ = uh.sendText(userID, fmt.Sprintf("🎉 %d day streak! Keep it up!", streak.Streak))
+		}
+	}
+
+	return formatGoalProgress(streak, done, today)
+}
+
+func (uh *UpdateHandler) goalProgress(ctx context.Context, streak *db.StreakModel) (string, error) {
+	today, done, err := uh.reviewsToday(ctx, streak.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	return formatGoalProgress(streak, done, today), nil
+}
+
+// reviewsToday returns today's date in the timezone of the user and how many
+// reviews they have done since midnight there.
+func (uh *UpdateHandler) reviewsToday(ctx context.Context, userID int64) (string, int, error) {
+	loc, err := uh.usersRepo.GetLocation(ctx, userID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	now := time.Now().In(loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	done, err := uh.reviewsRepo.CountSince(ctx, userID, midnight)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return now.Format(time.DateOnly), done, nil
+}
+
+func formatGoalProgress(streak *db.StreakModel, done int, today string) string {
+	progress := fmt.Sprintf("Today: %d/%d", done, streak.DailyGoal)
+	if done >= streak.DailyGoal {
+		progress += " ✅"
+	}
+
+	if current := streak.Current(today); current > 0 {
+		progress += fmt.Sprintf(" · 🔥 %d day streak", current)
+	}
+
+	return progress
+}
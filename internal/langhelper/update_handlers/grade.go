@@ -0,0 +1,133 @@
+package update_handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"math"
+	"strings"
+	"time"
+)
+
+const maxLatency = 10 * time.Minute
+
+// HandleGrade handles "/grade <grade> <card>" and "/grade_reverse <grade> <card>",
+// see cardRef and buttonRef, which are sent by the inline buttons under each
+// card. It reschedules the card and sends the next one.
+func (uh *UpdateHandler) HandleGrade(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleGrade",
+		"user_id": userID,
+	})
+
+	parts := strings.SplitN(strings.TrimSpace(text), " ", 3)
+	if len(parts) < 3 {
+		return errors.New("invalid command")
+	}
+
+	grade, err := db.ParseGrade(parts[1])
+	if err != nil {
+		return err
+	}
+
+	ref, ok := uh.resolveRef(userID, parts[2])
+	if !ok {
+		return uh.sendText(userID, expiredCardText)
+	}
+
+	word, sense := parseCardRef(ref)
+	card := sessionCard{word: word, reverse: parts[0] == GradeReverseCommand, sense: sense}
+	userWord, next, err := uh.review(ctx, userID, card, grade)
+	if err != nil {
+		entry.WithError(err).Error("failed to review word")
+		return err
+	}
+
+	reply := fmt.Sprintf("%s: next review in %s", uh.title(ctx, userID, userWord.Word), formatInterval(time.Until(next)))
+	if progress := uh.trackGoal(ctx, userID); progress != "" {
+		reply += "\n" + progress
+	}
+
+	if _, err = uh.updateFetcher.GetBot().Send(tgbotapi.NewMessage(userID, reply)); err != nil {
+		entry.WithError(err).Error("failed to send message")
+		return err
+	}
+
+	if session := uh.recordSessionAnswer(userWord, grade); session != nil {
+		return uh.sendSessionSummary(ctx, userID, session)
+	}
+
+	if uh.finishLeechDrill(userWord) {
+		return uh.nextLeech(ctx, userID)
+	}
+
+	return uh.HandleRandom(ctx, userID)
+}
+
+// review grades a card of userID with every scheduler and returns when it is
+// due again according to the scheduler the user has chosen.
+func (uh *UpdateHandler) review(ctx context.Context, userID int64, card sessionCard, grade db.Grade) (*db.UserWordModel, time.Time, error) {
+	userWord, err := uh.userWordsRepo.Get(ctx, userID, card.word, card.reverse, card.sense)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	scheduler, err := uh.usersRepo.GetScheduler(ctx, userID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	now := time.Now().In(time.UTC)
+	review := db.ReviewModel{
+		UserID:     userID,
+		Owner:      userWord.Owner,
+		Word:       card.word,
+		Reverse:    card.reverse,
+		Sense:      card.sense,
+		Grade:      grade,
+		ReviewedAt: now,
+	}
+	// last_asked is when the card was shown. Anything slower than
+	// maxLatency is someone who walked away, not a slow answer.
+	if latency := now.Sub(userWord.LastAsked); !userWord.LastAsked.IsZero() && latency < maxLatency {
+		review.Latency = latency
+	}
+
+	db.ReviewAll(userWord, grade, now)
+	becameLeech, err := uh.updateLeech(ctx, userWord, grade)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if err = uh.userWordsRepo.UpdateSchedule(ctx, userWord); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if becameLeech {
+		if err = uh.sendText(userID, fmt.Sprintf("You have forgotten %s %d times, it is a leech now. It's out of your reviews until you practice it with %s.",
+			uh.title(ctx, userID, card.word), userWord.Lapses, LeechesCommand)); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	if err = uh.reviewsRepo.Insert(ctx, review); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return userWord, scheduler.NextReview(userWord), nil
+}
+
+func formatInterval(d time.Duration) string {
+	days := int(math.Round(d.Hours() / 24))
+	switch {
+	case days < 1:
+		return "less than a day"
+	case days == 1:
+		return "1 day"
+	default:
+		return fmt.Sprintf("%d days", days)
+	}
+}
@@ -0,0 +1,160 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/language"
+	"strings"
+)
+
+// HandleLanguages handles "/languages <word language> [meaning language]",
+// which sets the BCP-47 tags of the language words posted in the chat are in
+// and of the one their meanings are written in, like "/languages de fa". A
+// caption can override them with its lang field. Without an argument it shows
+// them, changing them in a group takes an admin.
+func (uh *UpdateHandler) HandleLanguages(ctx context.Context, text string, chatID, senderID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleLanguages",
+		"chat_id": chatID,
+	})
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		source, target, err := uh.chatsRepo.GetLanguages(ctx, chatID)
+		if err != nil {
+			entry.WithError(err).Error("failed to get languages")
+			return err
+		}
+
+		return uh.sendText(chatID, fmt.Sprintf("%s Use %s <word language> [meaning language] to change it.",
+			languagesText(source, target), LanguagesCommand))
+	}
+
+	if len(fields) > 3 {
+		return uh.sendText(chatID, fmt.Sprintf("Use %s <word language> [meaning language], like %s de en.",
+			LanguagesCommand, LanguagesCommand))
+	}
+
+	if ok, err := uh.requireAdmin(chatID, senderID); !ok {
+		return err
+	}
+
+	tags := make([]string, 2)
+	for i, field := range fields[1:] {
+		tag, err := lang.Parse(field)
+		if err != nil {
+			return uh.sendText(chatID, fmt.Sprintf("%q is not a language tag, use one like en, de or fa.", field))
+		}
+		tags[i] = tag.String()
+	}
+
+	if err := uh.chatsRepo.SetLanguages(ctx, chatID, tags[0], tags[1]); err != nil {
+		entry.WithError(err).Error("failed to set languages")
+		return err
+	}
+
+	return uh.sendText(chatID, languagesText(tags[0], tags[1]))
+}
+
+func languagesText(source, target string) string {
+	if target == "" {
+		return fmt.Sprintf("Words posted here are in %s.", source)
+	}
+
+	return fmt.Sprintf("Words posted here are in %s with meanings in %s.", source, target)
+}
+
+// titleOf is the word of a card capitalized the way its language does it.
+func titleOf(word *db.WordsModel) string {
+	return lang.Title(lang.Of(word.SourceLang), word.Word)
+}
+
+// cardTitle is titleOf isolated from the text around it, see lang.Isolate.
+func cardTitle(word *db.WordsModel) string {
+	return lang.Isolate(lang.Of(word.SourceLang), titleOf(word))
+}
+
+// cardText isolates every line of text, which is in the language of the
+// meanings of word, so each of them reads in the direction of that language.
+func cardText(word *db.WordsModel, text string) string {
+	tag := lang.Of(word.TargetLang)
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = lang.Isolate(tag, lines[i])
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// title is titleOf for the word of a card of userID when only its name is at
+// hand.
+func (uh *UpdateHandler) title(ctx context.Context, userID int64, word string) string {
+	return lang.Title(uh.languageOf(ctx, userID, word), word)
+}
+
+// titles is title for many words of cards of userID, looked up at once.
+func (uh *UpdateHandler) titles(ctx context.Context, userID int64, words []string) (map[string]string, error) {
+	languages, err := uh.wordsRepo.GetLanguages(ctx, userID, words)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(words))
+	for _, word := range words {
+		res[word] = lang.Title(lang.Of(languages[word]), word)
+	}
+
+	return res, nil
+}
+
+// languageOf is the language of the word of a card of userID, lang.Default if
+// the word can't be found.
+func (uh *UpdateHandler) languageOf(ctx context.Context, userID int64, word string) language.Tag {
+	model, err := uh.wordsRepo.GetByWords(ctx, userID, word)
+	if err != nil {
+		return lang.Default
+	}
+
+	return lang.Of(model.SourceLang)
+}
+
+// wordName returns the name of the word someone typed in chatID as it is
+// stored: name itself if there is such a word, otherwise the word name is
+// typed differently, see WordsRepo.GetByName, trying the language of the chat
+// first. If there is none either it is name lower cased the way that language
+// does it.
+func (uh *UpdateHandler) wordName(ctx context.Context, chatID int64, name string) (string, error) {
+	if _, err := uh.wordsRepo.GetByWords(ctx, chatID, name); err == nil {
+		return name, nil
+	} else if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	tag, err := uh.chatLanguage(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+
+	word, err := uh.wordsRepo.GetByName(ctx, chatID, tag.String(), name)
+	if err == sql.ErrNoRows {
+		return lang.Lower(tag, name), nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return word.Word, nil
+}
+
+// chatLanguage is the language words posted in chatID are in.
+func (uh *UpdateHandler) chatLanguage(ctx context.Context, chatID int64) (language.Tag, error) {
+	source, _, err := uh.chatsRepo.GetLanguages(ctx, chatID)
+	if err != nil {
+		return language.Und, err
+	}
+
+	return lang.Of(source), nil
+}
@@ -0,0 +1,20 @@
+package update_handlers
+
+import (
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"testing"
+)
+
+func TestCardTextPersianMeaning(t *testing.T) {
+	word := &db.WordsModel{Word: "apple", SourceLang: "en", TargetLang: "fa"}
+
+	got := cardText(word, "1. سیب\n2. CPU")
+	want := "\u20671. سیب\u2069\n\u20672. CPU\u2069"
+	if got != want {
+		t.Errorf("cardText() = %q, want %q", got, want)
+	}
+
+	if got, want := cardTitle(word), "\u2066Apple\u2069"; got != want {
+		t.Errorf("cardTitle() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+// HandleLeeches starts the leech drill with "/leeches". "/leeches threshold 6"
+// sets after how many lapses a card becomes a leech, "off" turns it off.
+func (uh *UpdateHandler) HandleLeeches(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleLeeches",
+		"user_id": userID,
+	})
+
+	threshold, err := uh.usersRepo.GetLeechThreshold(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get leech threshold")
+		return err
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return uh.nextLeech(ctx, userID)
+	}
+
+	if len(fields) < 3 || strings.ToLower(fields[1]) != "threshold" {
+		var current string
+		if threshold > 0 {
+			current = fmt.Sprintf("A card becomes a leech after %d lapses.", threshold)
+		} else {
+			current = "Leeches are off."
+		}
+		return uh.sendText(userID, fmt.Sprintf("%s Use %s threshold <N>|off to change it.", current, LeechesCommand))
+	}
+
+	if strings.ToLower(fields[2]) == "off" {
+		threshold = 0
+	} else if threshold, err = strconv.Atoi(fields[2]); err != nil || threshold <= 0 {
+		return uh.sendText(userID, fmt.Sprintf("Invalid threshold %q.", fields[2]))
+	}
+
+	if err = uh.usersRepo.SetLeechThreshold(ctx, userID, threshold); err != nil {
+		entry.WithError(err).Error("failed to set leech threshold")
+		return err
+	}
+
+	if threshold == 0 {
+		return uh.sendText(userID, "Leeches are off.")
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("A card becomes a leech after %d lapses.", threshold))
+}
+
+// nextLeech shows the example and meaning of the next leech together, with a
+// button to be asked it once the user has studied it.
+func (uh *UpdateHandler) nextLeech(ctx context.Context, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.nextLeech",
+		"user_id": userID,
+	})
+
+	userWord, err := uh.userWordsRepo.GetLeech(ctx, userID)
+	if err == sql.ErrNoRows {
+		uh.states.update(userID, func(state *chatState) {
+			state.leech = nil
+		})
+		return uh.sendText(userID, "You have no leeches 🎉")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get leech")
+		return err
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, userWord.UserID, userWord.Word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	uh.states.update(userID, func(state *chatState) {
+		card := cardOf(userWord)
+		state.leech = &card
+	})
+
+	var (
+		text = fmt.Sprintf("🩹 Forgotten %d times, take a good look:\n\n%s\n%s",
+			userWord.Lapses, cardTitle(word), cardText(word, cardMeaning(word, userWord.Sense)))
+		fileID = cardFileID(word, userWord.Sense)
+		markup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Ask Me", LeechAskCommand),
+			),
+		)
+		msg tgbotapi.Chattable
+	)
+	if fileID != "" {
+		photo := tgbotapi.NewPhoto(userID, tgbotapi.FileID(fileID))
+		photo.Caption = text
+		photo.ReplyMarkup = markup
+		msg = photo
+	} else {
+		message := tgbotapi.NewMessage(userID, text)
+		message.ReplyMarkup = markup
+		msg = message
+	}
+
+	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send leech")
+		return err
+	}
+
+	return nil
+}
+
+// HandleLeechAsk asks the leech the user has just studied.
+func (uh *UpdateHandler) HandleLeechAsk(ctx context.Context, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleLeechAsk",
+		"user_id": userID,
+	})
+
+	card := uh.states.get(userID).leech
+	if card == nil {
+		return uh.nextLeech(ctx, userID)
+	}
+
+	userWord, err := uh.userWordsRepo.Get(ctx, userID, card.word, card.reverse, card.sense)
+	if err == sql.ErrNoRows {
+		return uh.nextLeech(ctx, userID)
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get leech")
+		return err
+	}
+
+	if err = uh.userWordsRepo.MarkAsked(ctx, userWord); err != nil {
+		entry.WithError(err).Error("failed to mark leech as asked")
+		return err
+	}
+
+	return uh.sendCard(ctx, userWord)
+}
+
+// finishLeechDrill reports whether userWord is the leech being drilled and
+// clears it if so.
+func (uh *UpdateHandler) finishLeechDrill(userWord *db.UserWordModel) bool {
+	var drilled bool
+	uh.states.update(userWord.UserID, func(state *chatState) {
+		if state.leech != nil && *state.leech == cardOf(userWord) {
+			drilled = true
+			state.leech = nil
+		}
+	})
+
+	return drilled
+}
+
+// updateLeech flags userWord as a leech once it has lapsed as many times as
+// the user allows and reports whether it just became one. A leech that is
+// remembered in the drill goes back to the normal reviews with a clean slate.
+func (uh *UpdateHandler) updateLeech(ctx context.Context, userWord *db.UserWordModel, grade db.Grade) (bool, error) {
+	if userWord.Leech {
+		if grade >= db.GradeGood {
+			userWord.Leech = false
+			userWord.Lapses = 0
+		}
+		return false, nil
+	}
+
+	threshold, err := uh.usersRepo.GetLeechThreshold(ctx, userWord.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	if threshold <= 0 || userWord.Lapses < threshold {
+		return false, nil
+	}
+
+	userWord.Leech = true
+	return true, nil
+}
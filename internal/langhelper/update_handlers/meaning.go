@@ -5,29 +5,46 @@ import (
 	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 	"strings"
 )
 
+// HandleMeaning handles "/meaning <card>" and "/meaning_with_example <card>",
+// see cardRef and buttonRef. It lists every sense of the word and points at
+// the one the card asks for.
 func (uh *UpdateHandler) HandleMeaning(ctx context.Context, text string, chatID int64) error {
 	entry := logrus.WithFields(logrus.Fields{
 		"spot": "UpdateHandler.HandleMeaning",
 	})
-	words := strings.Split(strings.TrimSpace(text), " ")
-	if len(words) < 2 {
+	command, ref, ok := strings.Cut(strings.TrimSpace(text), " ")
+	if !ok {
 		return errors.New("invalid command")
 	}
 
-	word, err := uh.wordsRepo.GetByWords(ctx, strings.ToLower(words[1]))
+	ref, ok = uh.resolveRef(chatID, ref)
+	if !ok {
+		return uh.sendText(chatID, expiredCardText)
+	}
+
+	name, sense := parseCardRef(ref)
+	name, err := uh.wordName(ctx, chatID, name)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word name")
+		return err
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, chatID, name)
 	if err != nil {
 		entry.WithError(err).Error("failed to get word")
 		return err
 	}
 
-	if words[0] == MeaningWithExampleCommand {
-		f := tgbotapi.FileID(word.FileID)
+	// words posted as text have no example photo, they get the meaning alone.
+	if fileID := cardFileID(word, sense); command == MeaningWithExampleCommand && fileID != "" {
+		f := tgbotapi.FileID(fileID)
 		if _, err = uh.updateFetcher.GetBot().Send(&tgbotapi.PhotoConfig{
 			BaseFile: tgbotapi.BaseFile{
 				BaseChat: tgbotapi.BaseChat{
@@ -35,7 +52,7 @@ func (uh *UpdateHandler) HandleMeaning(ctx context.Context, text string, chatID
 				},
 				File: f,
 			},
-			Caption: fmt.Sprintf("%s\n%s", cases.Title(language.English).String(word.Word), word.Meaning),
+			Caption: describeWord(word, sense),
 		}); err != nil {
 			entry.WithError(err).Error("failed to send message")
 			return err
@@ -48,7 +65,7 @@ func (uh *UpdateHandler) HandleMeaning(ctx context.Context, text string, chatID
 		BaseChat: tgbotapi.BaseChat{
 			ChatID: chatID,
 		},
-		Text: fmt.Sprintf("%s\n%s", cases.Title(language.English).String(word.Word), word.Meaning),
+		Text: describeWord(word, sense),
 	}); err != nil {
 		entry.WithError(err).Error("failed to send message")
 		return err
@@ -56,3 +73,95 @@ func (uh *UpdateHandler) HandleMeaning(ctx context.Context, text string, chatID
 
 	return nil
 }
+
+// HandleExample handles "/example <card>", see cardRef and buttonRef, which
+// sends the example photo of the card without its meaning or the word itself,
+// for the reverse cards that hide them.
+func (uh *UpdateHandler) HandleExample(ctx context.Context, text string, chatID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleExample",
+		"chat_id": chatID,
+	})
+
+	ref, ok := uh.resolveRef(chatID, strings.TrimSpace(strings.TrimPrefix(text, ExampleCommand)))
+	if !ok {
+		return uh.sendText(chatID, expiredCardText)
+	}
+
+	name, sense := parseCardRef(ref)
+	name, err := uh.wordName(ctx, chatID, name)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word name")
+		return err
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, chatID, name)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	fileID := cardFileID(word, sense)
+	if fileID == "" {
+		return uh.sendText(chatID, "This word has no example.")
+	}
+
+	if _, err = uh.updateFetcher.GetBot().Send(tgbotapi.NewPhoto(chatID, tgbotapi.FileID(fileID))); err != nil {
+		entry.WithError(err).Error("failed to send example")
+		return err
+	}
+
+	return nil
+}
+
+// describeWord shows word with its meaning and every field its caption had.
+// Senses are numbered, with an arrow at sense if it is not 0. The meaning is
+// laid out in the direction of its language and the rest in the one of the
+// word, see lang.Isolate.
+func describeWord(word *db.WordsModel, sense int) string {
+	source := lang.Of(word.SourceLang)
+
+	var sb strings.Builder
+	sb.WriteString(cardTitle(word))
+	if word.PartOfSpeech != "" {
+		sb.WriteString(fmt.Sprintf(" (%s)", word.PartOfSpeech))
+	}
+	if word.IPA != "" {
+		sb.WriteString(" " + lang.Isolate(source, word.IPA))
+	}
+	if len(word.Senses) > 1 {
+		for _, s := range word.Senses {
+			sb.WriteString("\n")
+			if s.Position == sense {
+				sb.WriteString("👉 ")
+			}
+			sb.WriteString(cardText(word, fmt.Sprintf("%d. %s", s.Position, s.Definition)))
+			if s.PartOfSpeech != "" {
+				sb.WriteString(fmt.Sprintf(" (%s)", s.PartOfSpeech))
+			}
+			if s.Example != "" {
+				sb.WriteString("\n   • " + lang.Isolate(source, s.Example))
+			}
+		}
+	} else {
+		sb.WriteString("\n" + cardText(word, word.Meaning))
+	}
+
+	if len(word.Synonyms) > 0 {
+		sb.WriteString("\nSynonyms: " + lang.Isolate(source, strings.Join(word.Synonyms, ", ")))
+	}
+	if len(word.Antonyms) > 0 {
+		sb.WriteString("\nAntonyms: " + lang.Isolate(source, strings.Join(word.Antonyms, ", ")))
+	}
+	for _, example := range word.Examples {
+		sb.WriteString("\n• " + lang.Isolate(source, example))
+	}
+	if len(word.Tags) > 0 {
+		sb.WriteString("\nTags: " + strings.Join(word.Tags, ", "))
+	}
+	if word.SourceChatTitle != "" {
+		sb.WriteString("\nFrom: " + lang.Isolate(language.Und, word.SourceChatTitle))
+	}
+
+	return sb.String()
+}
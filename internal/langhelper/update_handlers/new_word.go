@@ -2,44 +2,424 @@ package update_handlers
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/caption"
 	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/text/language"
 	"strings"
 	"time"
 )
 
-func (uh *UpdateHandler) HandleInsert(ctx context.Context, caption, fileID string) error {
+// HandleInsert adds the word described by the caption of a photo post or the
+// text of a text post, see the caption package for its format. fileID is empty
+// for text posts. If text can't be parsed, or the word already exists, the
+// poster is told in a reply to messageID.
+func (uh *UpdateHandler) HandleInsert(ctx context.Context, chatID int64, messageID int, text, fileID string) error {
+	reply, err := uh.insert(ctx, chatID, messageID, text, fileID)
+	if err != nil || reply == "" {
+		return err
+	}
+
+	return uh.replyTo(chatID, messageID, reply)
+}
+
+// insert is HandleInsert without sending the reply, which it returns instead.
+// The reply is empty when there is nothing to tell.
+func (uh *UpdateHandler) insert(ctx context.Context, chatID int64, messageID int, text, fileID string) (string, error) {
+	post, err := caption.Parse(text)
+	if err != nil {
+		return fmt.Sprintf("Couldn't add this word, %s", err), nil
+	}
+
+	return uh.insertEntry(ctx, chatID, messageID, fileID, caption.Entry{Line: 1, Caption: post})
+}
+
+// maxReportLen keeps the bulk insert report within a single message.
+const maxReportLen = 4000
+
+// HandleBulkInsert adds every word of a text post listing several words, see
+// caption.ParseList, in one transaction and replies with what happened to each
+// of them. A post with a single word is answered like HandleInsert does.
+func (uh *UpdateHandler) HandleBulkInsert(ctx context.Context, chatID int64, messageID int, text string) error {
+	reply, err := uh.bulkInsert(ctx, chatID, messageID, text)
+	if err != nil || reply == "" {
+		return err
+	}
+
+	return uh.replyTo(chatID, messageID, reply)
+}
+
+// bulkInsert is HandleBulkInsert without sending the reply, see insert.
+func (uh *UpdateHandler) bulkInsert(ctx context.Context, chatID int64, messageID int, text string) (string, error) {
+	entries := caption.ParseList(text)
+	if len(entries) == 0 {
+		return "", nil
+	}
+	if len(entries) == 1 {
+		if entries[0].Err == nil {
+			return uh.insertEntry(ctx, chatID, messageID, "", entries[0])
+		}
+		// a single line that isn't "word - meaning" is just a message.
+		if !strings.Contains(strings.TrimSpace(text), "\n") {
+			return "", nil
+		}
+		return uh.insert(ctx, chatID, messageID, text, "")
+	}
+
+	report, err := uh.saveEntries(ctx, chatID, messageID, "", entries, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return report.String(), nil
+}
+
+// insertEntry saves the only word of a post. A new word needs no answer,
+// anything else is worth telling.
+func (uh *UpdateHandler) insertEntry(ctx context.Context, chatID int64, messageID int, fileID string, e caption.Entry) (string, error) {
+	report, err := uh.saveEntries(ctx, chatID, messageID, fileID, []caption.Entry{e}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if report.added == 1 && report.homographs == 0 {
+		return "", nil
+	}
+
+	return strings.Replace(report.lines[0], fmt.Sprintf("line %d: ", e.Line), "", 1), nil
+}
+
+// HandleEditedPost applies the edit of a post to the words that were added
+// with it. Words are matched by name, or if the post had a single word and
+// still has one, the word is renamed. Other words are added. A post that didn't
+// add anything before, like one with a mistake in its caption, is handled as a
+// new post.
+func (uh *UpdateHandler) HandleEditedPost(ctx context.Context, msg *tgbotapi.Message) error {
 	entry := logrus.WithFields(logrus.Fields{
-		"spot": "UpdateHandler.HandleInsert",
+		"spot":    "UpdateHandler.HandleEditedPost",
+		"chat_id": msg.Chat.ID,
 	})
 
-	var (
-		words   = strings.Split(caption, "\n")
-		word    = strings.ToLower(words[0])
-		meaning = words[1]
-	)
-	if err := uh.wordsRepo.Insert(ctx, db.WordsModel{
-		Word:      word,
-		Meaning:   meaning,
-		FileID:    fileID,
-		CreatedAt: time.Now().In(time.UTC),
-	}); err != nil {
-		entry.WithError(err).Error("failed to insert word to db")
+	uh.rememberChat(ctx, msg.Chat)
+
+	text, fileID := msg.Text, ""
+	if len(msg.Photo) > 0 {
+		text, fileID = msg.Caption, msg.Photo[len(msg.Photo)-1].FileID
+	}
+	if text == "" || strings.HasPrefix(text, "/") {
+		return nil
+	}
+
+	existing, err := uh.wordsRepo.GetBySource(ctx, msg.Chat.ID, msg.MessageID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get words of post")
 		return err
 	}
 
-	users, err := uh.usersRepo.ListIDs(ctx)
+	if len(existing) == 0 {
+		if fileID != "" {
+			return uh.HandleInsert(ctx, msg.Chat.ID, msg.MessageID, text, fileID)
+		}
+		return uh.HandleBulkInsert(ctx, msg.Chat.ID, msg.MessageID, text)
+	}
+
+	var entries []caption.Entry
+	if fileID != "" {
+		post, err := caption.Parse(text)
+		entries = []caption.Entry{{Line: 1, Caption: post, Err: err}}
+	} else {
+		entries = caption.ParseList(text)
+	}
+
+	report, err := uh.saveEntries(ctx, msg.Chat.ID, msg.MessageID, fileID, entries, existing)
 	if err != nil {
-		entry.WithError(err).Error("failed to list user ids")
 		return err
 	}
 
-	if len(users) == 0 {
+	// a successful edit needs no answer.
+	if report.skipped == 0 && report.rejected == 0 {
 		return nil
 	}
 
-	if err = uh.userWordsRepo.InsertBulkSingleWord(ctx, word, users); err != nil {
-		entry.WithError(err).Error("failed to bulk insert in user_words repo")
+	return uh.replyTo(msg.Chat.ID, msg.MessageID, report.String())
+}
+
+type saveReport struct {
+	lines                             []string
+	added, updated, skipped, rejected int
+	// homographs is how many of added got a number as they already existed.
+	homographs int
+}
+
+func (r *saveReport) String() string {
+	var sb strings.Builder
+	if r.updated > 0 {
+		sb.WriteString(fmt.Sprintf("Added %d, updated %d, skipped %d, rejected %d.", r.added, r.updated, r.skipped, r.rejected))
+	} else {
+		sb.WriteString(fmt.Sprintf("Added %d, skipped %d, rejected %d.", r.added, r.skipped, r.rejected))
+	}
+
+	for _, line := range r.lines {
+		if sb.Len()+len(line) > maxReportLen {
+			sb.WriteString("\n…")
+			break
+		}
+		sb.WriteString("\n" + line)
+	}
+
+	return sb.String()
+}
+
+// saveEntries stores entries of the post messageID in one transaction. Entries
+// matching one of existing, the words the post added before, update it and the
+// rest are added. Words that already exist are handled by the duplicate policy
+// of the chat.
+func (uh *UpdateHandler) saveEntries(ctx context.Context, chatID int64, messageID int, fileID string, entries []caption.Entry, existing []db.WordsModel) (*saveReport, error) {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.saveEntries",
+		"chat_id": chatID,
+	})
+
+	policy, err := uh.chatsRepo.GetDuplicatePolicy(ctx, chatID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get duplicate policy")
+		return nil, err
+	}
+
+	deck, err := uh.chatsRepo.GetDeck(ctx, chatID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get deck")
+		return nil, err
+	}
+
+	owner, err := uh.ownerOf(ctx, chatID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get owner of words")
+		return nil, err
+	}
+
+	source, target, err := uh.chatsRepo.GetLanguages(ctx, chatID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get languages")
+		return nil, err
+	}
+
+	tx, err := uh.wordsRepo.BeginTx(ctx)
+	if err != nil {
+		entry.WithError(err).Error("failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &saveReport{}
+	for _, e := range entries {
+		if e.Err != nil {
+			report.rejected++
+			report.lines = append(report.lines, fmt.Sprintf("❌ %s", e.Err))
+			continue
+		}
+
+		model := wordModel(e.Caption, fileID, chatID, messageID, source, target)
+		model.Owner = owner
+		key := lang.Key(lang.Of(model.SourceLang), model.Word)
+		if key == "" {
+			report.rejected++
+			report.lines = append(report.lines, fmt.Sprintf("❌ line %d: %q is not a word", e.Line, e.Caption.Word))
+			continue
+		}
+
+		// the same word typed differently is that word.
+		name, err := uh.wordsRepo.KeyWordTx(ctx, tx, model.Owner, key)
+		if err == nil {
+			model.Word = name
+		} else if err != sql.ErrNoRows {
+			entry.WithError(err).Error("failed to get word by key")
+			return nil, err
+		}
+		if old := matchWord(model.Word, existing, len(entries)); old != nil {
+			if fileID == "" {
+				model.FileID = old.FileID
+			}
+			model.Owner = old.Owner
+
+			err = uh.wordsRepo.UpdateTx(ctx, tx, old.Word, model)
+			if errors.Is(err, db.ErrDuplicate) {
+				report.rejected++
+				report.lines = append(report.lines, fmt.Sprintf("❌ line %d: can't rename %s, %s already exists", e.Line, old.Word, model.Word))
+				continue
+			} else if err != nil {
+				entry.WithError(err).Error("failed to update word")
+				return nil, err
+			}
+
+			if err = uh.fileWordTx(ctx, tx, model.Owner, model.Word, wordDecks(deck, model.Tags), false); err != nil {
+				entry.WithError(err).Error("failed to file word")
+				return nil, err
+			}
+
+			report.updated++
+			report.lines = append(report.lines, fmt.Sprintf("✏️ line %d: %s", e.Line, model.Word))
+			continue
+		}
+
+		word, merged := model.Word, false
+		err = uh.wordsRepo.InsertTx(ctx, tx, model)
+		if errors.Is(err, db.ErrDuplicate) {
+			var source int64
+			if source, err = uh.wordsRepo.SourceChatTx(ctx, tx, model.Owner, model.Word); err != nil {
+				entry.WithError(err).Error("failed to get source chat of word")
+				return nil, err
+			}
+
+			// a word of another chat is shared with this one rather than
+			// changed, only a homograph is a word of this chat.
+			if source != 0 && source != chatID && policy != db.DuplicateHomograph {
+				if err = uh.fileWordTx(ctx, tx, model.Owner, model.Word, wordDecks(deck, model.Tags), true); err != nil {
+					entry.WithError(err).Error("failed to file word")
+					return nil, err
+				}
+
+				report.skipped++
+				report.lines = append(report.lines, fmt.Sprintf("🔗 line %d: %s already exists in another chat, it is added to %s", e.Line, model.Word, deck))
+				continue
+			}
+
+			word, err = uh.wordsRepo.MergeTx(ctx, tx, model, policy)
+			merged = true
+		}
+		if errors.Is(err, db.ErrDuplicate) {
+			report.skipped++
+			report.lines = append(report.lines, fmt.Sprintf("⏭ line %d: %s is a duplicate", e.Line, model.Word))
+			continue
+		} else if err != nil {
+			entry.WithError(err).Error("failed to insert word to db")
+			return nil, err
+		}
+
+		// replaced and appended words keep their cards, with new ones for
+		// new senses. An appended meaning adds the word to more decks rather
+		// than moving it.
+		if err = uh.fileWordTx(ctx, tx, model.Owner, word, wordDecks(deck, model.Tags), merged && policy == db.DuplicateAppend); err != nil {
+			entry.WithError(err).Error("failed to file word")
+			return nil, err
+		}
+		if merged && policy == db.DuplicateReplace {
+			report.updated++
+			report.lines = append(report.lines, fmt.Sprintf("✏️ line %d: %s is replaced", e.Line, word))
+			continue
+		}
+		if merged && policy == db.DuplicateAppend {
+			report.updated++
+			report.lines = append(report.lines, fmt.Sprintf("➕ line %d: %s got another meaning", e.Line, word))
+			continue
+		}
+
+		report.added++
+		if merged {
+			report.homographs++
+			report.lines = append(report.lines, fmt.Sprintf("✅ line %d: %s already exists, added as %s", e.Line, model.Word, word))
+			continue
+		}
+		report.lines = append(report.lines, fmt.Sprintf("✅ line %d: %s", e.Line, word))
+	}
+
+	if err = tx.Commit(); err != nil {
+		entry.WithError(err).Error("failed to commit words")
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// matchWord finds the word an entry of an edited post replaces. When the post
+// had one word and still has one, that is the word even if it was renamed.
+func matchWord(word string, existing []db.WordsModel, entries int) *db.WordsModel {
+	for i := range existing {
+		if existing[i].Word == word {
+			return &existing[i]
+		}
+	}
+
+	if len(existing) == 1 && entries == 1 {
+		return &existing[0]
+	}
+
+	return nil
+}
+
+// wordModel makes the word of c, posted in a chat whose words are in source
+// with meanings in target unless c says otherwise. The word is lower cased for
+// its language and the meaning normalized for its own.
+func wordModel(c *caption.Caption, fileID string, chatID int64, messageID int, source, target string) db.WordsModel {
+	if c.SourceLang != "" {
+		source = c.SourceLang
+	}
+	if c.TargetLang != "" {
+		target = c.TargetLang
+	}
+
+	meaningLang := lang.Of(target)
+	return db.WordsModel{
+		Word:            lang.Lower(lang.Of(source), c.Word),
+		Meaning:         lang.Normalize(meaningLang, c.Meaning),
+		FileID:          fileID,
+		CreatedAt:       time.Now().In(time.UTC),
+		PartOfSpeech:    c.PartOfSpeech,
+		IPA:             c.IPA,
+		Synonyms:        c.Synonyms,
+		Antonyms:        c.Antonyms,
+		Examples:        c.Examples,
+		Tags:            c.Tags,
+		Senses:          senseModels(meaningLang, c.Senses),
+		SourceChatID:    chatID,
+		SourceMessageID: messageID,
+		SourceLang:      source,
+		TargetLang:      target,
+	}
+}
+
+func senseModels(tag language.Tag, senses []caption.Sense) []db.SenseModel {
+	var models []db.SenseModel
+	for i, sense := range senses {
+		models = append(models, db.SenseModel{
+			Position:     i + 1,
+			Definition:   lang.Normalize(tag, sense.Definition),
+			PartOfSpeech: sense.PartOfSpeech,
+			Example:      sense.Example,
+		})
+	}
+
+	return models
+}
+
+// rememberChat records the title of the chat a post came from, which names
+// its deck. Failing to is only logged, the post is still handled.
+func (uh *UpdateHandler) rememberChat(ctx context.Context, chat *tgbotapi.Chat) {
+	if chat == nil || chat.Title == "" {
+		return
+	}
+
+	if err := uh.chatsRepo.SetTitle(ctx, chat.ID, chat.Title); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"spot":    "UpdateHandler.rememberChat",
+			"chat_id": chat.ID,
+		}).WithError(err).Error("failed to set chat title")
+	}
+}
+
+func (uh *UpdateHandler) replyTo(chatID int64, messageID int, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyToMessageID = messageID
+	if _, err := uh.updateFetcher.GetBot().Send(msg); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"spot":    "UpdateHandler.replyTo",
+			"chat_id": chatID,
+		}).WithError(err).Error("failed to send reply")
 		return err
 	}
 
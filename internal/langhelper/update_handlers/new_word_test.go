@@ -0,0 +1,175 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	_ "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestHandler returns an UpdateHandler on a fresh database and no bot, for
+// the parts of handlers that don't send anything.
+func newTestHandler(t *testing.T) *UpdateHandler {
+	t.Helper()
+
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sqlite.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	wordsRepo, err := db.NewWordsRepo(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userWordsRepo, err := db.NewUserWordRepo(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usersRepo, err := db.NewUsersRepo(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviewsRepo, err := db.NewReviewsRepo(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chatsRepo, err := db.NewChatsRepo(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decksRepo, err := db.NewDecksRepo(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewUpdateHandler(nil, wordsRepo, userWordsRepo, usersRepo, reviewsRepo, chatsRepo, decksRepo)
+}
+
+func TestBulkInsert(t *testing.T) {
+	const channel = -100
+
+	tests := []struct {
+		name  string
+		text  string
+		reply string
+		words []string
+	}{
+		{"one line", "apple - a fruit", "", []string{"apple"}},
+		{"several lines", "apple - a fruit\nbank - the land beside a river", "Added 2, skipped 0, rejected 0.", []string{"apple", "bank"}},
+		{"caption", "apple\na round fruit\npos: noun", "", []string{"apple"}},
+		{"broken caption", "apple\npos: noun", "Couldn't add this word, line 2", nil},
+		{"message", "hello there", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			uh := newTestHandler(t)
+
+			reply, err := uh.bulkInsert(ctx, channel, 1, tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(reply, tt.reply) || (tt.reply == "" && reply != "") {
+				t.Errorf("got reply %q, want %q", reply, tt.reply)
+			}
+
+			for _, word := range tt.words {
+				if _, err = uh.wordsRepo.GetByWords(ctx, channel, word); err != nil {
+					t.Errorf("%s wasn't added: %v", word, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkInsertDuplicate(t *testing.T) {
+	ctx := context.Background()
+	uh := newTestHandler(t)
+
+	if _, err := uh.bulkInsert(ctx, -100, 1, "apple - a fruit"); err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := uh.bulkInsert(ctx, -100, 2, "Apple - a fruit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "⏭ apple is a duplicate" {
+		t.Errorf("got reply %q, want the duplicate to be reported", reply)
+	}
+}
+
+func TestBulkInsertTypedDifferently(t *testing.T) {
+	ctx := context.Background()
+	uh := newTestHandler(t)
+
+	if _, err := uh.bulkInsert(ctx, -100, 1, "apple - a fruit"); err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := uh.bulkInsert(ctx, -100, 2, "“Apple.” - a fruit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "⏭ apple is a duplicate" {
+		t.Errorf("got reply %q, want the word to be taken for apple", reply)
+	}
+}
+
+func TestBulkInsertKeepsGermanCase(t *testing.T) {
+	ctx := context.Background()
+	uh := newTestHandler(t)
+
+	if err := uh.chatsRepo.SetLanguages(ctx, -100, "de", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uh.bulkInsert(ctx, -100, 1, "Essen - food\nessen - to eat"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Essen", "essen"} {
+		if _, err := uh.wordsRepo.GetByWords(ctx, -100, name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	word, err := uh.wordsRepo.GetByName(ctx, -100, "de", "„essen“")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if word.Word != "essen" {
+		t.Errorf("got %s, want the exact match essen", word.Word)
+	}
+}
+
+func TestBulkInsertReplaceOtherChat(t *testing.T) {
+	ctx := context.Background()
+	uh := newTestHandler(t)
+
+	if _, err := uh.bulkInsert(ctx, -100, 1, "apple - a fruit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := uh.chatsRepo.SetDuplicatePolicy(ctx, -200, db.DuplicateReplace); err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := uh.bulkInsert(ctx, -200, 1, "apple - a company")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply, "already exists in another chat") {
+		t.Errorf("got reply %q, want the word to be shared", reply)
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, -100, "apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if word.Meaning != "a fruit" {
+		t.Errorf("apple means %q, another chat replaced it", word.Meaning)
+	}
+}
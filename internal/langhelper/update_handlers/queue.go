@@ -0,0 +1,166 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errNothingDue is returned by nextCard when there are no due cards left or
+// the user has reached their daily limits.
+var errNothingDue = errors.New("nothing due")
+
+// nextCard picks the next card of the user: due reviews until the daily review
+// limit is reached, then new cards until the daily new card limit is reached.
+// It returns sql.ErrNoRows if the user hasn't started the bot.
+func (uh *UpdateHandler) nextCard(ctx context.Context, userID int64, withReverse bool) (*db.UserWordModel, error) {
+	limits, err := uh.usersRepo.GetLimits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduler, err := uh.usersRepo.GetScheduler(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := uh.usersRepo.GetLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	newToday, reviewsToday, err := uh.reviewsRepo.CountQueuesSince(ctx, userID, midnight)
+	if err != nil {
+		return nil, err
+	}
+
+	if reviewsToday < limits.Reviews {
+		userWord, err := uh.userWordsRepo.GetRandomWord(ctx, userID, scheduler, withReverse, db.QueueReview)
+		if err != sql.ErrNoRows {
+			return userWord, err
+		}
+	}
+
+	if newToday < limits.New {
+		userWord, err := uh.userWordsRepo.GetRandomWord(ctx, userID, scheduler, withReverse, db.QueueNew)
+		if err != sql.ErrNoRows {
+			return userWord, err
+		}
+	}
+
+	return nil, errNothingDue
+}
+
+// CountDue returns how many cards the user has due at at: the due reviews and
+// the new cards, up to what is left of the daily new card limit today.
+func (uh *UpdateHandler) CountDue(ctx context.Context, userID int64, at time.Time) (int, error) {
+	limits, err := uh.usersRepo.GetLimits(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	scheduler, err := uh.usersRepo.GetScheduler(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	loc, err := uh.usersRepo.GetLocation(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().In(loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	newToday, _, err := uh.reviewsRepo.CountQueuesSince(ctx, userID, midnight)
+	if err != nil {
+		return 0, err
+	}
+
+	return uh.userWordsRepo.CountDue(ctx, userID, scheduler, at, max(0, limits.New-newToday))
+}
+
+// cardLabel tells the user whether they are seeing a card for the first time
+// or drilling a leech.
+func cardLabel(userWord *db.UserWordModel) string {
+	if userWord.Leech {
+		return "🩹 Leech"
+	}
+	if userWord.IsNew() {
+		return "🆕 New"
+	}
+
+	return "🔁 Review"
+}
+
+// HandleLimits sets the daily limits with "/limits new 10" and
+// "/limits review 100". Without arguments it shows them.
+func (uh *UpdateHandler) HandleLimits(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleLimits",
+		"user_id": userID,
+	})
+
+	limits, err := uh.usersRepo.GetLimits(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get limits")
+		return err
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 3 {
+		return uh.sendText(userID, fmt.Sprintf("You get up to %d new words and %d reviews a day.\nUse %s new|review <N> to change them.",
+			limits.New, limits.Reviews, LimitsCommand))
+	}
+
+	limit, err := strconv.Atoi(fields[2])
+	if err != nil || limit < 0 {
+		return uh.sendText(userID, fmt.Sprintf("Invalid limit %q.", fields[2]))
+	}
+
+	switch strings.ToLower(fields[1]) {
+	case "new":
+		err = uh.usersRepo.SetNewLimit(ctx, userID, limit)
+	case "review", "reviews":
+		err = uh.usersRepo.SetReviewLimit(ctx, userID, limit)
+	default:
+		return uh.sendText(userID, fmt.Sprintf("Use %s new|review <N>.", LimitsCommand))
+	}
+	if err != nil {
+		entry.WithError(err).Error("failed to set limit")
+		return err
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("Daily %s limit is %d.", strings.ToLower(fields[1]), limit))
+}
+
+// sendCaughtUp tells the user there is nothing left for today and ends the
+// running session, if any.
+func (uh *UpdateHandler) sendCaughtUp(ctx context.Context, userID int64) error {
+	var session *reviewSession
+	uh.states.update(userID, func(state *chatState) {
+		session, state.session = state.session, nil
+		if session != nil {
+			state.missed = session.missed
+		}
+	})
+
+	if err := uh.sendText(userID, fmt.Sprintf("You're all caught up for today! Use %s to change your daily limits.", LimitsCommand)); err != nil {
+		return err
+	}
+
+	if session != nil && session.answered > 0 {
+		return uh.sendSessionSummary(ctx, userID, session)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package update_handlers
+
+import (
+	"context"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"testing"
+	"time"
+)
+
+func TestCountDueNewLimit(t *testing.T) {
+	ctx := context.Background()
+	uh := newTestHandler(t)
+
+	if err := uh.usersRepo.Insert(ctx, db.UsersModel{UserID: 1, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uh.bulkInsert(ctx, -100, 1, "apple - a fruit\npear - a fruit\nplum - a fruit"); err != nil {
+		t.Fatal(err)
+	}
+
+	deck, err := uh.chatsRepo.GetDeck(ctx, -100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words, err := uh.decksRepo.Subscribe(ctx, 1, deck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = uh.userWordsRepo.InsertBulkSingleUser(ctx, 1, words); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		limit, want int
+	}{
+		{1, 1},
+		{20, 3},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if err = uh.usersRepo.SetNewLimit(ctx, 1, tt.limit); err != nil {
+			t.Fatal(err)
+		}
+
+		due, err := uh.CountDue(ctx, 1, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if due != tt.want {
+			t.Errorf("new limit %d: got %d due, want %d", tt.limit, due, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"strings"
+)
+
+const (
+	quizOptions = 4
+	// telegram rejects callback data longer than this.
+	maxCallbackDataLen = 64
+)
+
+// HandleQuiz asks the next word of the user as a multiple choice question.
+// The wrong options are meanings of other words.
+func (uh *UpdateHandler) HandleQuiz(ctx context.Context, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleQuiz",
+		"user_id": userID,
+	})
+
+	userWord, err := uh.nextCard(ctx, userID, false)
+	if err == errNothingDue {
+		return uh.sendCaughtUp(ctx, userID)
+	} else if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get a random word")
+		return err
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, userWord.UserID, userWord.Word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	// fetch more than needed so the options are not the same every time.
+	distractors, err := uh.wordsRepo.GetDistractors(ctx, userID, *word, (quizOptions-1)*3)
+	if err != nil {
+		entry.WithError(err).Error("failed to get distractors")
+		return err
+	}
+	rand.Shuffle(len(distractors), func(i, j int) {
+		distractors[i], distractors[j] = distractors[j], distractors[i]
+	})
+
+	ref := cardRef(word.Word, userWord.Sense)
+	options := []quizOption{{word: word.Word, meaning: cardMeaning(word, userWord.Sense)}}
+	for _, d := range distractors {
+		if len(options) == quizOptions {
+			break
+		}
+		if len(quizAnswerData(ref, d.Word)) > maxCallbackDataLen {
+			continue
+		}
+		// the first sense, so words with several don't stand out.
+		options = append(options, quizOption{word: d.Word, meaning: db.SplitMeaning(d.Meaning)[0]})
+	}
+
+	if len(options) < 2 || len(quizAnswerData(ref, word.Word)) > maxCallbackDataLen {
+		return uh.sendText(userID, "There are not enough words for a quiz yet.")
+	}
+	rand.Shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(options))
+	for _, option := range options {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(option.meaning, quizAnswerData(ref, option.word)),
+		))
+	}
+
+	text := fmt.Sprintf("%s\n\n%s", cardLabel(userWord), cardTitle(word))
+	if hint := senseHint(word, userWord.Sense); hint != "" {
+		text += " " + hint
+	}
+
+	msg := tgbotapi.NewMessage(userID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send quiz")
+		return err
+	}
+
+	return nil
+}
+
+// HandleQuizAnswer checks the option picked under a quiz, records it as a
+// review and replaces the options with the result.
+func (uh *UpdateHandler) HandleQuizAnswer(ctx context.Context, text string, userID int64, messageID int) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleQuizAnswer",
+		"user_id": userID,
+	})
+
+	ref, chosen, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(text, QuizAnswerCommand)), "|")
+	if !ok {
+		return errors.New("invalid command")
+	}
+	word, sense := parseCardRef(ref)
+
+	correct, err := uh.wordsRepo.GetByWords(ctx, userID, word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	grade := db.GradeGood
+	result := fmt.Sprintf("%s\n\n✅ %s", cardTitle(correct), cardText(correct, cardMeaning(correct, sense)))
+	if chosen != word {
+		grade = db.GradeAgain
+		picked, err := uh.wordsRepo.GetByWords(ctx, userID, chosen)
+		if err != nil {
+			entry.WithError(err).Error("failed to get chosen word")
+			return err
+		}
+		result = fmt.Sprintf("%s\n\n❌ %s\n✅ %s", cardTitle(correct),
+			cardText(picked, db.SplitMeaning(picked.Meaning)[0]), cardText(correct, cardMeaning(correct, sense)))
+	}
+
+	if _, _, err = uh.review(ctx, userID, sessionCard{word: word, sense: sense}, grade); err != nil {
+		entry.WithError(err).Error("failed to review word")
+		return err
+	}
+	if progress := uh.trackGoal(ctx, userID); progress != "" {
+		result += "\n\n" + progress
+	}
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(userID, messageID, result, tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Next Question", QuizCommand),
+		),
+	))
+	if _, err = uh.updateFetcher.GetBot().Send(edit); err != nil {
+		entry.WithError(err).Error("failed to edit quiz")
+		return err
+	}
+
+	return nil
+}
+
+// quizOption is a meaning to choose from and the word it belongs to.
+type quizOption struct {
+	word, meaning string
+}
+
+// quizAnswerData is the callback data of choosing the meaning of chosen for
+// the card ref, see cardRef.
+func quizAnswerData(ref, chosen string) string {
+	return fmt.Sprintf("%s %s|%s", QuizAnswerCommand, ref, chosen)
+}
@@ -0,0 +1,30 @@
+package update_handlers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDistractorsSamePartOfSpeech(t *testing.T) {
+	ctx := context.Background()
+	uh := newTestHandler(t)
+
+	if _, err := uh.bulkInsert(ctx, -100, 1, "apple\na round fruit\npos: noun\n\n"+
+		"run\nto go quickly\npos: verb\n\n"+
+		"pear\na green fruit with a long shape\npos: noun"); err != nil {
+		t.Fatal(err)
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, -100, "apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	distractors, err := uh.wordsRepo.GetDistractors(ctx, -100, *word, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(distractors) != 1 || distractors[0].Word != "pear" {
+		t.Errorf("got distractors %v, want the other noun pear", distractors)
+	}
+}
@@ -6,9 +6,9 @@ import (
 
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+	"html"
 )
 
 func (uh *UpdateHandler) HandleRandom(ctx context.Context, userID int64) error {
@@ -17,8 +17,13 @@ func (uh *UpdateHandler) HandleRandom(ctx context.Context, userID int64) error {
 		"user_id": userID,
 	})
 
-	word, err := uh.userWordsRepo.GetRandomWord(ctx, userID)
-	if err != nil && err != sql.ErrNoRows {
+	word, err := uh.nextSessionCard(ctx, userID)
+	if err == nil && word == nil {
+		word, err = uh.nextCard(ctx, userID, true)
+	}
+	if err == errNothingDue {
+		return uh.sendCaughtUp(ctx, userID)
+	} else if err != nil && err != sql.ErrNoRows {
 		entry.WithError(err).Errorln("failed to get a random word")
 		return err
 	} else if err == sql.ErrNoRows {
@@ -33,18 +38,48 @@ func (uh *UpdateHandler) HandleRandom(ctx context.Context, userID int64) error {
 		return nil
 	}
 
-	msg := tgbotapi.NewMessage(userID, cases.Title(language.English).String(word.Word))
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Show Meaning", fmt.Sprintf("/meaning %s", word.Word)),
-		),
+	return uh.sendCard(ctx, word)
+}
+
+// sendCard asks word with the grade buttons under it.
+func (uh *UpdateHandler) sendCard(ctx context.Context, word *db.UserWordModel) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.sendCard",
+		"user_id": word.UserID,
+	})
+
+	if word.Reverse {
+		return uh.sendReverseCard(ctx, word)
+	}
+
+	model, err := uh.wordsRepo.GetByWords(ctx, word.UserID, word.Word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	text := fmt.Sprintf("%s%s\n\n%s", uh.sessionProgress(word.UserID), cardLabel(word), cardTitle(model))
+	// a card of a single sense says which one it asks for.
+	if hint := senseHint(model, word.Sense); hint != "" {
+		text += " " + hint
+	}
+
+	ref := cardRef(word.Word, word.Sense)
+	rows := [][]tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Show Meaning (With Example)", fmt.Sprintf("/meaning_with_example %s", word.Word)),
+			tgbotapi.NewInlineKeyboardButtonData("Show Meaning", uh.callbackData(word.UserID, MeaningCommand, ref)),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Next Word", "/random"),
+			tgbotapi.NewInlineKeyboardButtonData("Show Meaning (With Example)", uh.callbackData(word.UserID, MeaningWithExampleCommand, ref)),
 		),
-	)
+		uh.gradeRow(word),
+	}
+	if row := uh.actionRow(word); row != nil {
+		rows = append(rows, row)
+	}
+
+	msg := tgbotapi.NewMessage(word.UserID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
 		entry.WithError(err).Error("failed to send random word")
 		return err
@@ -52,3 +87,59 @@ func (uh *UpdateHandler) HandleRandom(ctx context.Context, userID int64) error {
 
 	return nil
 }
+
+// sendReverseCard shows the meaning of the word and hides the word itself
+// behind a spoiler.
+func (uh *UpdateHandler) sendReverseCard(ctx context.Context, userWord *db.UserWordModel) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.sendReverseCard",
+		"user_id": userWord.UserID,
+	})
+
+	word, err := uh.wordsRepo.GetByWords(ctx, userWord.UserID, userWord.Word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	// the example alone, a meaning with it would give the answer away.
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if cardFileID(word, userWord.Sense) != "" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Show Example", uh.callbackData(userWord.UserID, ExampleCommand, cardRef(word.Word, userWord.Sense))),
+		))
+	}
+	rows = append(rows, uh.gradeRow(userWord))
+	if row := uh.actionRow(userWord); row != nil {
+		rows = append(rows, row)
+	}
+
+	msg := tgbotapi.NewMessage(userWord.UserID, fmt.Sprintf("%s%s\n\n%s\n\n<tg-spoiler>%s</tg-spoiler>",
+		html.EscapeString(uh.sessionProgress(userWord.UserID)), cardLabel(userWord), html.EscapeString(cardText(word, cardMeaning(word, userWord.Sense))),
+		html.EscapeString(cardTitle(word))))
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send reverse card")
+		return err
+	}
+
+	return nil
+}
+
+// gradeRow holds the grade buttons under a card.
+func (uh *UpdateHandler) gradeRow(userWord *db.UserWordModel) []tgbotapi.InlineKeyboardButton {
+	command := GradeCommand
+	if userWord.Reverse {
+		command = GradeReverseCommand
+	}
+
+	// "again" is the longest grade, so the four buttons can share one ref.
+	ref := uh.buttonRef(userWord.UserID, fmt.Sprintf("%s %s", command, db.GradeAgain), cardRef(userWord.Word, userWord.Sense))
+	return tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Again", fmt.Sprintf("%s %s %s", command, db.GradeAgain, ref)),
+		tgbotapi.NewInlineKeyboardButtonData("Hard", fmt.Sprintf("%s %s %s", command, db.GradeHard, ref)),
+		tgbotapi.NewInlineKeyboardButtonData("Good", fmt.Sprintf("%s %s %s", command, db.GradeGood, ref)),
+		tgbotapi.NewInlineKeyboardButtonData("Easy", fmt.Sprintf("%s %s %s", command, db.GradeEasy, ref)),
+	)
+}
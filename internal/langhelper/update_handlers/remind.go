@@ -0,0 +1,128 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/itzloop/langhelperbot/internal/langhelper/reminder_handler"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// HandleRemind sets the daily review reminder with "/remind 08:30" and turns it
+// off with "/remind off". Without an argument it shows the current settings.
+func (uh *UpdateHandler) HandleRemind(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleRemind",
+		"user_id": userID,
+	})
+
+	reminder, err := uh.usersRepo.GetReminder(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get reminder")
+		return err
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		status := "You have no daily reminder."
+		if reminder.RemindAt != "" {
+			status = fmt.Sprintf("You are reminded every day at %s (%s).", reminder.RemindAt, reminder.Timezone)
+		}
+		if reminder.QuietStart != "" {
+			status += fmt.Sprintf("\nQuiet hours: %s-%s.", reminder.QuietStart, reminder.QuietEnd)
+		}
+
+		return uh.sendText(userID, fmt.Sprintf("%s\nUse %s HH:MM or %s off to change it and %s to set your timezone.",
+			status, RemindCommand, RemindCommand, TimezoneCommand))
+	}
+
+	remindAt := ""
+	if !strings.EqualFold(fields[1], "off") {
+		t, err := time.Parse(reminder_handler.ClockLayout, fields[1])
+		if err != nil {
+			return uh.sendText(userID, fmt.Sprintf("Invalid time %q, use HH:MM like %s 08:30.", fields[1], RemindCommand))
+		}
+		remindAt = t.Format(reminder_handler.ClockLayout)
+	}
+
+	if err = uh.usersRepo.SetRemindAt(ctx, userID, remindAt); err != nil {
+		entry.WithError(err).Error("failed to set reminder")
+		return err
+	}
+
+	if remindAt == "" {
+		return uh.sendText(userID, "Daily reminder is off.")
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("You will be reminded every day at %s (%s).", remindAt, reminder.Timezone))
+}
+
+// HandleTimezone sets the IANA timezone of the user, e.g. "/timezone Asia/Tehran".
+func (uh *UpdateHandler) HandleTimezone(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleTimezone",
+		"user_id": userID,
+	})
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return uh.sendText(userID, fmt.Sprintf("Use %s <IANA timezone>, e.g. %s Europe/Berlin.", TimezoneCommand, TimezoneCommand))
+	}
+
+	loc, err := time.LoadLocation(fields[1])
+	if err != nil {
+		return uh.sendText(userID, fmt.Sprintf("Unknown timezone %q.", fields[1]))
+	}
+
+	if err = uh.usersRepo.SetTimezone(ctx, userID, loc.String()); err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to set timezone")
+		return err
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("Your timezone is %s, it is %s there now.",
+		loc.String(), time.Now().In(loc).Format(reminder_handler.ClockLayout)))
+}
+
+// HandleQuiet sets the hours reminders are not sent in with "/quiet 22:00-07:00"
+// and removes them with "/quiet off".
+func (uh *UpdateHandler) HandleQuiet(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleQuiet",
+		"user_id": userID,
+	})
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return uh.sendText(userID, fmt.Sprintf("Use %s HH:MM-HH:MM or %s off.", QuietCommand, QuietCommand))
+	}
+
+	var start, end string
+	if !strings.EqualFold(fields[1], "off") {
+		from, to, ok := strings.Cut(fields[1], "-")
+		s, err1 := time.Parse(reminder_handler.ClockLayout, from)
+		e, err2 := time.Parse(reminder_handler.ClockLayout, to)
+		if !ok || err1 != nil || err2 != nil {
+			return uh.sendText(userID, fmt.Sprintf("Invalid quiet hours %q, use HH:MM-HH:MM like %s 22:00-07:00.", fields[1], QuietCommand))
+		}
+		start, end = s.Format(reminder_handler.ClockLayout), e.Format(reminder_handler.ClockLayout)
+	}
+
+	if err := uh.usersRepo.SetQuietHours(ctx, userID, start, end); err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to set quiet hours")
+		return err
+	}
+
+	if start == "" {
+		return uh.sendText(userID, "Quiet hours are off.")
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("No reminders between %s and %s.", start, end))
+}
@@ -0,0 +1,60 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// HandleReverse turns reverse (meaning to word) cards on or off with
+// "/reverse on" and "/reverse off". Without an argument it shows the setting.
+// A deck after the setting changes it for that deck only.
+func (uh *UpdateHandler) HandleReverse(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleReverse",
+		"user_id": userID,
+	})
+
+	enabled, err := uh.usersRepo.GetReverseCards(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get reverse cards setting")
+		return err
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return uh.sendText(userID, fmt.Sprintf("Reverse cards are %s. Use %s on|off to change it, or %s on|off|default <deck> for a deck.", onOff(enabled), ReverseCommand, ReverseCommand))
+	}
+
+	if len(fields) > 2 {
+		return uh.reverseOfDeck(ctx, userID, strings.ToLower(fields[1]), strings.Join(fields[2:], " "))
+	}
+
+	switch strings.ToLower(fields[1]) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return uh.sendText(userID, fmt.Sprintf("Use %s on|off.", ReverseCommand))
+	}
+
+	if err = uh.usersRepo.SetReverseCards(ctx, userID, enabled); err != nil {
+		entry.WithError(err).Error("failed to set reverse cards setting")
+		return err
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("Reverse cards are %s.", onOff(enabled)))
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+
+	return "off"
+}
@@ -0,0 +1,64 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// HandleScheduler lists the available schedulers with "/scheduler" and
+// switches the user to another one with "/scheduler <name>".
+func (uh *UpdateHandler) HandleScheduler(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleScheduler",
+		"user_id": userID,
+	})
+
+	current, err := uh.usersRepo.GetScheduler(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get scheduler")
+		return err
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		var (
+			sb   strings.Builder
+			rows [][]tgbotapi.InlineKeyboardButton
+		)
+		sb.WriteString(fmt.Sprintf("You are using %s.\n", current.Name()))
+		for _, s := range db.Schedulers {
+			sb.WriteString(fmt.Sprintf("\n%s: %s", s.Name(), s.Description()))
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(s.Name(), fmt.Sprintf("%s %s", SchedulerCommand, s.Name())),
+			))
+		}
+
+		msg := tgbotapi.NewMessage(userID, sb.String())
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+		if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+			entry.WithError(err).Error("failed to send message")
+			return err
+		}
+
+		return nil
+	}
+
+	scheduler, ok := db.LookupScheduler(strings.ToLower(fields[1]))
+	if !ok {
+		return uh.sendText(userID, fmt.Sprintf("Unknown scheduler %q, send %s to see the options.", fields[1], SchedulerCommand))
+	}
+
+	if err = uh.usersRepo.SetScheduler(ctx, userID, scheduler.Name()); err != nil {
+		entry.WithError(err).Error("failed to set scheduler")
+		return err
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("Switched to %s. Your review history is kept.", scheduler.Name()))
+}
@@ -0,0 +1,64 @@
+package update_handlers
+
+import (
+	"fmt"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"strconv"
+	"strings"
+)
+
+// cardRef refers to a card in commands and callback data: the word alone for
+// a card of the whole word or "#2 word" for a card of its second sense.
+func cardRef(word string, sense int) string {
+	if sense == 0 {
+		return word
+	}
+
+	return fmt.Sprintf("#%d %s", sense, word)
+}
+
+// parseCardRef parses what cardRef returns.
+func parseCardRef(ref string) (string, int) {
+	ref = strings.TrimSpace(ref)
+	if number, word, ok := strings.Cut(ref, " "); ok && strings.HasPrefix(number, "#") {
+		if sense, err := strconv.Atoi(number[1:]); err == nil && sense > 0 {
+			return strings.TrimSpace(word), sense
+		}
+	}
+
+	return ref, 0
+}
+
+// cardMeaning is what a card of sense asks for, the whole meaning for sense 0.
+func cardMeaning(word *db.WordsModel, sense int) string {
+	if s := word.Sense(sense); s != nil {
+		return s.Definition
+	}
+
+	return word.Meaning
+}
+
+// cardFileID is the example photo of sense, the one of the word if the sense
+// has none.
+func cardFileID(word *db.WordsModel, sense int) string {
+	if s := word.Sense(sense); s != nil && s.FileID != "" {
+		return s.FileID
+	}
+
+	return word.FileID
+}
+
+// senseHint tells which sense a card asks for, like "(2 of 3, verb)". It is
+// empty for cards of the whole word.
+func senseHint(word *db.WordsModel, sense int) string {
+	s := word.Sense(sense)
+	if s == nil || len(word.Senses) < 2 {
+		return ""
+	}
+
+	if s.PartOfSpeech != "" {
+		return fmt.Sprintf("(%d of %d, %s)", sense, len(word.Senses), s.PartOfSpeech)
+	}
+
+	return fmt.Sprintf("(%d of %d)", sense, len(word.Senses))
+}
@@ -0,0 +1,191 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSessionSize = 20
+	maxSessionSize     = 200
+)
+
+type sessionCard struct {
+	word    string
+	reverse bool
+	// sense is the sense of word the card asks, 0 for all of them.
+	sense int
+}
+
+func cardOf(userWord *db.UserWordModel) sessionCard {
+	return sessionCard{word: userWord.Word, reverse: userWord.Reverse, sense: userWord.Sense}
+}
+
+// reviewSession is a run of cards asked back to back with /review.
+type reviewSession struct {
+	size     int
+	answered int
+	correct  int
+	started  time.Time
+	// queue are cards to ask before letting the scheduler pick.
+	queue  []sessionCard
+	missed []sessionCard
+}
+
+// HandleReview starts a session of "/review [N]" cards, due cards first.
+func (uh *UpdateHandler) HandleReview(ctx context.Context, text string, userID int64) error {
+	size := defaultSessionSize
+	if fields := strings.Fields(text); len(fields) > 1 {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 || n > maxSessionSize {
+			return uh.sendText(userID, fmt.Sprintf("Use %s [N] with N between 1 and %d.", ReviewCommand, maxSessionSize))
+		}
+		size = n
+	}
+
+	uh.states.update(userID, func(state *chatState) {
+		state.session = &reviewSession{size: size, started: time.Now()}
+	})
+
+	return uh.HandleRandom(ctx, userID)
+}
+
+// HandleReviewMissed starts a session with the cards missed in the last one.
+func (uh *UpdateHandler) HandleReviewMissed(ctx context.Context, userID int64) error {
+	var missed []sessionCard
+	uh.states.update(userID, func(state *chatState) {
+		missed, state.missed = state.missed, nil
+		if len(missed) > 0 {
+			state.session = &reviewSession{size: len(missed), started: time.Now(), queue: missed}
+		}
+	})
+
+	if len(missed) == 0 {
+		return uh.sendText(userID, "There is nothing to review again.")
+	}
+
+	return uh.HandleRandom(ctx, userID)
+}
+
+// nextSessionCard returns the next queued card of the running session, nil if
+// there is none and the scheduler should pick.
+func (uh *UpdateHandler) nextSessionCard(ctx context.Context, userID int64) (*db.UserWordModel, error) {
+	var (
+		card sessionCard
+		ok   bool
+	)
+	uh.states.update(userID, func(state *chatState) {
+		if state.session != nil && len(state.session.queue) > 0 {
+			card, ok = state.session.queue[0], true
+			state.session.queue = state.session.queue[1:]
+		}
+	})
+	if !ok {
+		return nil, nil
+	}
+
+	userWord, err := uh.userWordsRepo.Get(ctx, userID, card.word, card.reverse, card.sense)
+	if err == sql.ErrNoRows {
+		// the word is gone, move on.
+		return uh.nextSessionCard(ctx, userID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return userWord, uh.userWordsRepo.MarkAsked(ctx, userWord)
+}
+
+// sessionProgress returns "[3/10] " for a card of a running session.
+func (uh *UpdateHandler) sessionProgress(userID int64) string {
+	session := uh.states.get(userID).session
+	if session == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("[%d/%d] ", session.answered+1, session.size)
+}
+
+// recordSessionAnswer counts an answer towards the running session. If that
+// was the last card it ends the session and returns it.
+func (uh *UpdateHandler) recordSessionAnswer(userWord *db.UserWordModel, grade db.Grade) *reviewSession {
+	var finished *reviewSession
+	uh.states.update(userWord.UserID, func(state *chatState) {
+		session := state.session
+		if session == nil {
+			return
+		}
+
+		session.answered++
+		if grade == db.GradeAgain {
+			session.missed = append(session.missed, cardOf(userWord))
+		} else {
+			session.correct++
+		}
+
+		if session.answered >= session.size {
+			finished = session
+			state.session = nil
+			state.missed = session.missed
+		}
+	})
+
+	return finished
+}
+
+func (uh *UpdateHandler) sendSessionSummary(ctx context.Context, userID int64, session *reviewSession) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.sendSessionSummary",
+		"user_id": userID,
+	})
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Session done! %d cards in %s", session.answered, time.Since(session.started).Round(time.Second)))
+	if session.answered > 0 {
+		sb.WriteString(fmt.Sprintf(", %s correct.", percent(float64(session.correct)/float64(session.answered))))
+	}
+
+	if len(session.missed) == 0 {
+		return uh.sendText(userID, sb.String())
+	}
+
+	words := make([]string, 0, len(session.missed))
+	for _, card := range session.missed {
+		words = append(words, card.word)
+	}
+	titles, err := uh.titles(ctx, userID, words)
+	if err != nil {
+		entry.WithError(err).Error("failed to get languages")
+		return err
+	}
+
+	sb.WriteString("\n\nMissed:")
+	for _, card := range session.missed {
+		sb.WriteString("\n• " + titles[card.word])
+		if card.sense > 0 {
+			sb.WriteString(fmt.Sprintf(" (sense %d)", card.sense))
+		}
+		if card.reverse {
+			sb.WriteString(" (reverse)")
+		}
+	}
+
+	msg := tgbotapi.NewMessage(userID, sb.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Review Missed Again", ReviewMissedCommand),
+		),
+	)
+	if _, err := uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send session summary")
+		return err
+	}
+
+	return nil
+}
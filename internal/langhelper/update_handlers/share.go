@@ -0,0 +1,79 @@
+package update_handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// HandleShare handles "/share on|off" in a private chat, which sets whether
+// words posted there from now on are shared with everyone or kept for the
+// user. Without an argument it shows the setting.
+func (uh *UpdateHandler) HandleShare(ctx context.Context, text string, chatID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleShare",
+		"chat_id": chatID,
+	})
+
+	if !isPrivate(chatID) {
+		return uh.sendText(chatID, "Words posted in channels and groups are always shared.")
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		shared, err := uh.chatsRepo.GetShared(ctx, chatID)
+		if err != nil {
+			entry.WithError(err).Error("failed to get shared setting")
+			return err
+		}
+
+		return uh.sendText(chatID, fmt.Sprintf("%s Use %s on|off to change it.", sharedText(shared), ShareCommand))
+	}
+
+	var shared bool
+	switch strings.ToLower(fields[1]) {
+	case "on":
+		shared = true
+	case "off":
+		shared = false
+	default:
+		return uh.sendText(chatID, fmt.Sprintf("Use %s on|off.", ShareCommand))
+	}
+
+	if err := uh.chatsRepo.SetShared(ctx, chatID, shared); err != nil {
+		entry.WithError(err).Error("failed to set shared setting")
+		return err
+	}
+
+	return uh.sendText(chatID, sharedText(shared))
+}
+
+// ownerOf returns who words posted in chatID belong to: the user of a private
+// chat unless they share their words, nobody for channels and groups.
+func (uh *UpdateHandler) ownerOf(ctx context.Context, chatID int64) (int64, error) {
+	if !isPrivate(chatID) {
+		return 0, nil
+	}
+
+	shared, err := uh.chatsRepo.GetShared(ctx, chatID)
+	if err != nil || shared {
+		return 0, err
+	}
+
+	return chatID, nil
+}
+
+// isPrivate reports whether chatID is a private chat, whose id is the one of
+// the user. Channels and groups have negative ids.
+func isPrivate(chatID int64) bool {
+	return chatID > 0
+}
+
+func sharedText(shared bool) string {
+	if shared {
+		return "Words you post here are shared with everyone."
+	}
+
+	return "Words you post here are only yours."
+}
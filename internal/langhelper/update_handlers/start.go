@@ -2,12 +2,17 @@ package update_handlers
 
 import (
 	"context"
+	"fmt"
 	"github.com/itzloop/langhelperbot/internal/langhelper/db"
 	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func (uh *UpdateHandler) HandleStart(ctx context.Context, userID int64) error {
+// HandleStart registers the user. "/start <chat id>", which is what the link
+// from /chat_deck sends, subscribes them to the deck of that chat too.
+func (uh *UpdateHandler) HandleStart(ctx context.Context, text string, userID int64) error {
 	entry := logrus.WithFields(logrus.Fields{
 		"spot":    "UpdateHandler.HandleStart",
 		"user_id": userID,
@@ -21,22 +26,43 @@ func (uh *UpdateHandler) HandleStart(ctx context.Context, userID int64) error {
 		return err
 	}
 
-	// TODO get all the words
-	words, err := uh.wordsRepo.GetAllWords(ctx)
-	if err != nil {
-		entry.WithError(err).Error("failed to get all words")
+	// whoever sends /start has not blocked us (anymore).
+	if err := uh.usersRepo.SetBlocked(ctx, userID, false); err != nil {
+		entry.WithError(err).Error("failed to unblock user")
 		return err
 	}
 
-	if len(words) == 0 {
-		return nil
+	var deck string
+	if chatID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(text, StartCommand)), 10, 64); err == nil {
+		if deck, err = uh.chatsRepo.GetDeck(ctx, chatID); err != nil {
+			entry.WithError(err).Error("failed to get deck")
+			return err
+		}
+
+		if _, err = uh.decksRepo.Subscribe(ctx, userID, deck); err != nil {
+			entry.WithError(err).Error("failed to subscribe")
+			return err
+		}
 	}
 
-	// TODO insert all the words in userWords
-	if err = uh.userWordsRepo.InsertBulkSingleUser(ctx, userID, words); err != nil {
-		entry.WithError(err).Error("failed to insert bulk single user")
+	// new users get the default deck unless they came for another one,
+	// everyone gets the words of their decks they are missing.
+	words, err := uh.decksRepo.SubscribeDefault(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to subscribe to the default deck")
 		return err
 	}
 
+	if len(words) > 0 {
+		if err = uh.userWordsRepo.InsertBulkSingleUser(ctx, userID, words); err != nil {
+			entry.WithError(err).Error("failed to insert bulk single user")
+			return err
+		}
+	}
+
+	if deck != "" {
+		return uh.sendText(userID, fmt.Sprintf("You are subscribed to %s, see %s for the other decks.", deck, DecksCommand))
+	}
+
 	return nil
 }
@@ -0,0 +1,78 @@
+package update_handlers
+
+import "sync"
+
+// chatState is what the bot remembers about a conversation between updates.
+type chatState struct {
+	// awaitingAnswer is the card the user was asked to type the word of.
+	awaitingAnswer *sessionCard
+	// session is the running /review session, if any.
+	session *reviewSession
+	// missed are the cards missed in the last finished session.
+	missed []sessionCard
+	// leech is the card being drilled with /leeches.
+	leech *sessionCard
+	// refs are the card refs too long for callback data by their short ref
+	// number, see UpdateHandler.buttonRef. lastRef is the last number given.
+	refs    map[int]string
+	lastRef int
+}
+
+func (s *chatState) isEmpty() bool {
+	return s.awaitingAnswer == nil && s.session == nil && len(s.missed) == 0 && s.leech == nil && len(s.refs) == 0
+}
+
+type chatStates struct {
+	mu     sync.Mutex
+	states map[int64]chatState
+}
+
+func newChatStates() *chatStates {
+	return &chatStates{states: make(map[int64]chatState)}
+}
+
+func (cs *chatStates) get(chatID int64) chatState {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return cs.states[chatID]
+}
+
+func (cs *chatStates) update(chatID int64, fn func(state *chatState)) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	state := cs.states[chatID]
+	fn(&state)
+	if state.isEmpty() {
+		delete(cs.states, chatID)
+		return
+	}
+	cs.states[chatID] = state
+}
+
+// addRef keeps ref for chatID and returns the number it is kept under. Only
+// the last maxShortRefs of them are kept.
+func (cs *chatStates) addRef(chatID int64, ref string) int {
+	var number int
+	cs.update(chatID, func(state *chatState) {
+		if state.refs == nil {
+			state.refs = make(map[int]string)
+		}
+		state.lastRef++
+		number = state.lastRef
+		state.refs[number] = ref
+		delete(state.refs, number-maxShortRefs)
+	})
+
+	return number
+}
+
+// ref returns the ref kept under number for chatID.
+func (cs *chatStates) ref(chatID int64, number int) (string, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	ref, ok := cs.states[chatID].refs[number]
+	return ref, ok
+}
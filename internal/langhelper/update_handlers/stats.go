@@ -0,0 +1,101 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const mostFailedCount = 5
+
+// HandleStats sends the user a summary of their review history. With
+// "/stats chart" it sends charts instead.
+func (uh *UpdateHandler) HandleStats(ctx context.Context, text string, userID int64) error {
+	if fields := strings.Fields(text); len(fields) > 1 && strings.EqualFold(fields[1], "chart") {
+		return uh.HandleStatsChart(ctx, userID)
+	}
+
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleStats",
+		"user_id": userID,
+	})
+
+	loc, err := uh.usersRepo.GetLocation(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get location")
+		return err
+	}
+
+	stats, err := uh.reviewsRepo.GetStats(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get stats")
+		return err
+	}
+
+	now := time.Now().In(loc)
+	endOfToday := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	due, err := uh.CountDue(ctx, userID, endOfToday)
+	if err != nil {
+		entry.WithError(err).Error("failed to count due words")
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Reviews: %d", stats.Total))
+	if stats.Total > 0 {
+		sb.WriteString(fmt.Sprintf(" (%s correct)", percent(float64(stats.Correct)/float64(stats.Total))))
+	}
+	sb.WriteString(fmt.Sprintf("\nDue today: %d", due))
+	if stats.AvgLatency > 0 {
+		sb.WriteString(fmt.Sprintf("\nAverage answer time: %.1fs", stats.AvgLatency.Seconds()))
+	}
+
+	sb.WriteString("\nRetention:")
+	for _, days := range []int{7, 30} {
+		retention, n, err := uh.reviewsRepo.Retention(ctx, userID, now.AddDate(0, 0, -days))
+		if err != nil {
+			entry.WithError(err).Error("failed to get retention")
+			return err
+		}
+
+		if n == 0 {
+			sb.WriteString(fmt.Sprintf(" %d days -", days))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(" %d days %s", days, percent(retention)))
+	}
+
+	failed, err := uh.reviewsRepo.MostFailed(ctx, userID, mostFailedCount)
+	if err != nil {
+		entry.WithError(err).Error("failed to get most failed words")
+		return err
+	}
+
+	if len(failed) > 0 {
+		words := make([]string, 0, len(failed))
+		for _, f := range failed {
+			words = append(words, f.Word)
+		}
+		titles, err := uh.titles(ctx, userID, words)
+		if err != nil {
+			entry.WithError(err).Error("failed to get languages")
+			return err
+		}
+
+		sb.WriteString("\n\nMost failed:")
+		for i, f := range failed {
+			sb.WriteString(fmt.Sprintf("\n%d. %s (%d)", i+1, titles[f.Word], f.Failures))
+		}
+	}
+
+	return uh.sendText(userID, sb.String())
+}
+
+func percent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
+}
@@ -0,0 +1,168 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/charts"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/sirupsen/logrus"
+	"image"
+	"time"
+)
+
+const forecastDays = 30
+
+// retentionBuckets group reviews by how many days passed since the card was
+// reviewed before. Each bucket holds reviews with fewer days than its limit.
+var retentionBuckets = []struct {
+	label string
+	limit float64
+}{
+	{"<1d", 1},
+	{"1d", 2},
+	{"2-3d", 4},
+	{"4-7d", 8},
+	{"1-2w", 15},
+	{"2-4w", 31},
+	{"1m+", 1 << 30},
+}
+
+// HandleStatsChart sends the review heatmap, the due forecast and the
+// retention curve of the user as an album.
+func (uh *UpdateHandler) HandleStatsChart(ctx context.Context, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleStatsChart",
+		"user_id": userID,
+	})
+
+	loc, err := uh.usersRepo.GetLocation(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get location")
+		return err
+	}
+
+	scheduler, err := uh.usersRepo.GetScheduler(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get scheduler")
+		return err
+	}
+
+	reviews, err := uh.reviewsRepo.List(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to list reviews")
+		return err
+	}
+
+	dueDates, err := uh.userWordsRepo.DueDates(ctx, userID, scheduler)
+	if err != nil {
+		entry.WithError(err).Error("failed to get due dates")
+		return err
+	}
+
+	var (
+		now   = time.Now().In(loc)
+		today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		imgs  = []image.Image{
+			charts.Heatmap(reviewsPerDay(reviews, loc), now),
+			charts.Forecast(dueForecast(dueDates, today), today),
+			charts.RetentionCurve(retentionCurve(reviews)),
+		}
+		media = make([]interface{}, 0, len(imgs))
+	)
+	for i, img := range imgs {
+		b, err := charts.Encode(img)
+		if err != nil {
+			entry.WithError(err).Error("failed to encode chart")
+			return err
+		}
+
+		photo := tgbotapi.NewInputMediaPhoto(tgbotapi.FileBytes{Name: "chart.png", Bytes: b})
+		if i == 0 {
+			photo.Caption = "Your progress"
+		}
+		media = append(media, photo)
+	}
+
+	if _, err = uh.updateFetcher.GetBot().SendMediaGroup(tgbotapi.NewMediaGroup(userID, media)); err != nil {
+		entry.WithError(err).Error("failed to send charts")
+		return err
+	}
+
+	return nil
+}
+
+func reviewsPerDay(reviews []db.ReviewModel, loc *time.Location) map[string]int {
+	counts := make(map[string]int)
+	for _, review := range reviews {
+		counts[review.ReviewedAt.In(loc).Format(time.DateOnly)]++
+	}
+
+	return counts
+}
+
+// dueForecast counts the cards due on each of the next forecastDays days.
+// Overdue cards are due today.
+func dueForecast(dueDates []time.Time, today time.Time) []int {
+	counts := make([]int, forecastDays)
+	for _, due := range dueDates {
+		day := 0
+		if due.After(today) {
+			day = int(due.Sub(today).Hours() / 24)
+		}
+
+		if day < forecastDays {
+			counts[day]++
+		}
+	}
+
+	return counts
+}
+
+// retentionCurve is the share of reviews remembered by the number of days since
+// the card was reviewed before. The first review of a card is not counted.
+func retentionCurve(reviews []db.ReviewModel) []charts.RetentionPoint {
+	type card struct {
+		word    string
+		reverse bool
+		sense   int
+	}
+
+	var (
+		last    = make(map[card]time.Time)
+		total   = make([]int, len(retentionBuckets))
+		correct = make([]int, len(retentionBuckets))
+	)
+	for _, review := range reviews {
+		c := card{word: review.Word, reverse: review.Reverse, sense: review.Sense}
+		prev, ok := last[c]
+		last[c] = review.ReviewedAt
+		if !ok {
+			continue
+		}
+
+		days := review.ReviewedAt.Sub(prev).Hours() / 24
+		for i, bucket := range retentionBuckets {
+			if days < bucket.limit {
+				total[i]++
+				if review.Grade != db.GradeAgain {
+					correct[i]++
+				}
+				break
+			}
+		}
+	}
+
+	points := make([]charts.RetentionPoint, 0, len(retentionBuckets))
+	for i, bucket := range retentionBuckets {
+		point := charts.RetentionPoint{Label: bucket.label, Reviews: total[i]}
+		if total[i] > 0 {
+			point.Retention = float64(correct[i]) / float64(total[i])
+		}
+		points = append(points, point)
+	}
+
+	return points
+}
@@ -0,0 +1,287 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxUnsuspendButtons keeps the keyboard under the suspended list short.
+const maxUnsuspendButtons = 20
+
+// HandleSuspend handles "/suspend <word>", which takes the word out of
+// rotation until it is unsuspended, and sends the next card.
+func (uh *UpdateHandler) HandleSuspend(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleSuspend",
+		"user_id": userID,
+	})
+
+	word, ok := uh.cardArgument(userID, text)
+	if !ok {
+		return uh.sendText(userID, expiredCardText)
+	} else if word == "" {
+		return uh.sendText(userID, fmt.Sprintf("Use %s <word>.", SuspendCommand))
+	}
+
+	word, err := uh.wordName(ctx, userID, word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word name")
+		return err
+	}
+
+	err = uh.userWordsRepo.Suspend(ctx, userID, word)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, fmt.Sprintf("You don't have %q.", word))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to suspend word")
+		return err
+	}
+
+	if err = uh.sendText(userID, fmt.Sprintf("%s is suspended, use %s to get it back.",
+		uh.title(ctx, userID, word), SuspendedCommand)); err != nil {
+		return err
+	}
+
+	return uh.HandleRandom(ctx, userID)
+}
+
+// HandleBury handles "/bury <word>", which hides the word until tomorrow in
+// the user's timezone, and sends the next card.
+func (uh *UpdateHandler) HandleBury(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleBury",
+		"user_id": userID,
+	})
+
+	word, ok := uh.cardArgument(userID, text)
+	if !ok {
+		return uh.sendText(userID, expiredCardText)
+	} else if word == "" {
+		return uh.sendText(userID, fmt.Sprintf("Use %s <word>.", BuryCommand))
+	}
+
+	word, err := uh.wordName(ctx, userID, word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word name")
+		return err
+	}
+
+	loc, err := uh.usersRepo.GetLocation(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get location")
+		return err
+	}
+
+	now := time.Now().In(loc)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	err = uh.userWordsRepo.Bury(ctx, userID, word, tomorrow)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, fmt.Sprintf("You don't have %q.", word))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to bury word")
+		return err
+	}
+
+	if err = uh.sendText(userID, fmt.Sprintf("%s is buried until tomorrow.", uh.title(ctx, userID, word))); err != nil {
+		return err
+	}
+
+	return uh.HandleRandom(ctx, userID)
+}
+
+// HandleKnown handles "/known <word>", which schedules every card of the word
+// far ahead, and sends the next card.
+func (uh *UpdateHandler) HandleKnown(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleKnown",
+		"user_id": userID,
+	})
+
+	word, ok := uh.cardArgument(userID, text)
+	if !ok {
+		return uh.sendText(userID, expiredCardText)
+	} else if word == "" {
+		return uh.sendText(userID, fmt.Sprintf("Use %s <word>.", KnownCommand))
+	}
+
+	word, err := uh.wordName(ctx, userID, word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word name")
+		return err
+	}
+
+	cards, err := uh.userWordsRepo.GetCards(ctx, userID, word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get cards")
+		return err
+	} else if len(cards) == 0 {
+		return uh.sendText(userID, fmt.Sprintf("You don't have %q.", word))
+	}
+
+	now := time.Now().In(time.UTC)
+	for i := range cards {
+		db.MarkKnown(&cards[i], now)
+		if err = uh.userWordsRepo.UpdateSchedule(ctx, &cards[i]); err != nil {
+			entry.WithError(err).Error("failed to update schedule")
+			return err
+		}
+	}
+
+	if err := uh.sendText(userID, fmt.Sprintf("%s: next review in %s", uh.title(ctx, userID, word),
+		formatInterval(db.KnownInterval*24*time.Hour))); err != nil {
+		return err
+	}
+
+	return uh.HandleRandom(ctx, userID)
+}
+
+// HandleUnsuspend handles "/unsuspend <word>" and puts a suspended or buried
+// word back into rotation.
+func (uh *UpdateHandler) HandleUnsuspend(ctx context.Context, text string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleUnsuspend",
+		"user_id": userID,
+	})
+
+	word := wordArgument(text)
+	if word == "" {
+		return uh.sendText(userID, fmt.Sprintf("Use %s <word>.", UnsuspendCommand))
+	}
+
+	word, err := uh.wordName(ctx, userID, word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word name")
+		return err
+	}
+
+	err = uh.userWordsRepo.Unsuspend(ctx, userID, word)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, fmt.Sprintf("You don't have %q.", word))
+	} else if err != nil {
+		entry.WithError(err).Error("failed to unsuspend word")
+		return err
+	}
+
+	return uh.sendText(userID, fmt.Sprintf("%s is back in rotation.", uh.title(ctx, userID, word)))
+}
+
+// HandleSuspended lists the suspended and buried words with a button to
+// unsuspend each of them.
+func (uh *UpdateHandler) HandleSuspended(ctx context.Context, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleSuspended",
+		"user_id": userID,
+	})
+
+	loc, err := uh.usersRepo.GetLocation(ctx, userID)
+	if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get location")
+		return err
+	}
+
+	list, err := uh.userWordsRepo.ListSuspended(ctx, userID, time.Now())
+	if err != nil {
+		entry.WithError(err).Error("failed to list suspended words")
+		return err
+	}
+
+	if len(list) == 0 {
+		return uh.sendText(userID, "You have no suspended or buried words.")
+	}
+
+	tag, err := uh.chatLanguage(ctx, userID)
+	if err != nil {
+		entry.WithError(err).Error("failed to get language")
+		return err
+	}
+	less := lang.Less(tag)
+	sort.SliceStable(list, func(i, j int) bool { return less(list[i].Word, list[j].Word) })
+
+	words := make([]string, 0, len(list))
+	for _, word := range list {
+		words = append(words, word.Word)
+	}
+	titles, err := uh.titles(ctx, userID, words)
+	if err != nil {
+		entry.WithError(err).Error("failed to get languages")
+		return err
+	}
+
+	var (
+		sb   strings.Builder
+		rows [][]tgbotapi.InlineKeyboardButton
+	)
+	sb.WriteString("Suspended and buried words:")
+	for _, word := range list {
+		title := titles[word.Word]
+		if word.Suspended {
+			sb.WriteString(fmt.Sprintf("\n• %s", title))
+		} else {
+			sb.WriteString(fmt.Sprintf("\n• %s (buried until %s)", title, word.BuriedUntil.In(loc).Format("Jan 2 15:04")))
+		}
+
+		data := fmt.Sprintf("%s %s", UnsuspendCommand, word.Word)
+		if len(rows) < maxUnsuspendButtons && len(data) <= maxCallbackDataLen {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Unsuspend "+title, data),
+			))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(userID, sb.String())
+	if len(rows) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send suspended words")
+		return err
+	}
+
+	return nil
+}
+
+// actionRow holds the buttons under a card that take its word out of
+// rotation, nil if their callback data doesn't fit even with a short ref, see
+// buttonRef.
+func (uh *UpdateHandler) actionRow(userWord *db.UserWordModel) []tgbotapi.InlineKeyboardButton {
+	// "/suspend" is the longest command, so the three buttons can share one ref.
+	ref := uh.buttonRef(userWord.UserID, SuspendCommand, userWord.Word)
+	if data := fmt.Sprintf("%s %s", SuspendCommand, ref); len(data) > maxCallbackDataLen {
+		return nil
+	}
+
+	return tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Suspend", fmt.Sprintf("%s %s", SuspendCommand, ref)),
+		tgbotapi.NewInlineKeyboardButtonData("Bury", fmt.Sprintf("%s %s", BuryCommand, ref)),
+		tgbotapi.NewInlineKeyboardButtonData("I know it", fmt.Sprintf("%s %s", KnownCommand, ref)),
+	)
+}
+
+// wordArgument returns what follows the command in "/command <word>".
+func wordArgument(text string) string {
+	parts := strings.SplitN(strings.TrimSpace(text), " ", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return strings.TrimSpace(parts[1])
+}
+
+// cardArgument is wordArgument for the commands sent by the buttons under a
+// card, whose word may be a short ref, see buttonRef. It returns false if the
+// short ref is gone.
+func (uh *UpdateHandler) cardArgument(chatID int64, text string) (string, bool) {
+	return uh.resolveRef(chatID, wordArgument(text))
+}
@@ -0,0 +1,28 @@
+package update_handlers
+
+import (
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"strings"
+	"testing"
+)
+
+func TestActionRow(t *testing.T) {
+	uh := &UpdateHandler{states: newChatStates()}
+	for _, word := range []string{"apple", strings.Repeat("a", maxCallbackDataLen)} {
+		row := uh.actionRow(&db.UserWordModel{UserID: 1, Word: word})
+		if len(row) != 3 {
+			t.Fatalf("actionRow(%q) has %d buttons, want 3", word, len(row))
+		}
+
+		for _, button := range row {
+			data := *button.CallbackData
+			if len(data) > maxCallbackDataLen {
+				t.Fatalf("callback data %q is %d bytes", data, len(data))
+			}
+
+			if got, ok := uh.cardArgument(1, data); !ok || got != word {
+				t.Fatalf("cardArgument(%q) = %q, %v, want %q", data, got, ok, word)
+			}
+		}
+	}
+}
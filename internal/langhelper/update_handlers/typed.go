@@ -0,0 +1,196 @@
+package update_handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/itzloop/langhelperbot/internal/langhelper/fuzzy"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
+	"github.com/sirupsen/logrus"
+	"html"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// typoRunes is how many characters of a word may be wrong per typo allowed.
+const typoRunes = 6
+
+// HandleType sends the meaning of the next word, with its example photo if it
+// has one, and waits for the user to type the word.
+func (uh *UpdateHandler) HandleType(ctx context.Context, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleType",
+		"user_id": userID,
+	})
+
+	userWord, err := uh.nextCard(ctx, userID, false)
+	if err == errNothingDue {
+		return uh.sendCaughtUp(ctx, userID)
+	} else if err == sql.ErrNoRows {
+		return uh.sendText(userID, "You need to start the bot first to use this feature.")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to get a random word")
+		return err
+	}
+
+	word, err := uh.wordsRepo.GetByWords(ctx, userWord.UserID, userWord.Word)
+	if err != nil {
+		entry.WithError(err).Error("failed to get word")
+		return err
+	}
+
+	var (
+		prompt = fmt.Sprintf("%s\n\nType the word:\n%s", cardLabel(userWord), cardText(word, cardMeaning(word, userWord.Sense)))
+		fileID = cardFileID(word, userWord.Sense)
+		markup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Show Answer", TypeSkipCommand),
+			),
+		)
+		msg tgbotapi.Chattable
+	)
+	if fileID != "" {
+		photo := tgbotapi.NewPhoto(userID, tgbotapi.FileID(fileID))
+		photo.Caption = prompt
+		photo.ReplyMarkup = markup
+		msg = photo
+	} else {
+		text := tgbotapi.NewMessage(userID, prompt)
+		text.ReplyMarkup = markup
+		msg = text
+	}
+
+	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send message")
+		return err
+	}
+
+	uh.states.update(userID, func(state *chatState) {
+		card := cardOf(userWord)
+		state.awaitingAnswer = &card
+	})
+
+	return nil
+}
+
+// HandleTypedAnswer grades what the user typed against the word they were
+// asked and replies with the mistakes.
+func (uh *UpdateHandler) HandleTypedAnswer(ctx context.Context, answer string, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleTypedAnswer",
+		"user_id": userID,
+	})
+
+	var card *sessionCard
+	uh.states.update(userID, func(state *chatState) {
+		card, state.awaitingAnswer = state.awaitingAnswer, nil
+	})
+	if card == nil {
+		return nil
+	}
+
+	var (
+		word     = card.word
+		tag      = uh.languageOf(ctx, userID, word)
+		expected = lang.FoldedKey(tag, word)
+		got      = lang.FoldedKey(tag, answer)
+		distance = fuzzy.Distance(got, expected)
+		allowed  = max(1, utf8.RuneCountInString(expected)/typoRunes)
+		grade    db.Grade
+		text     string
+	)
+	switch {
+	case distance == 0:
+		grade = db.GradeGood
+		text = fmt.Sprintf("✅ <b>%s</b>", html.EscapeString(lang.Title(tag, word)))
+	case distance <= allowed:
+		grade = db.GradeHard
+		text = fmt.Sprintf("Almost! %s\n<b>%s</b>", renderDiff(got, expected), html.EscapeString(lang.Title(tag, word)))
+	default:
+		grade = db.GradeAgain
+		text = fmt.Sprintf("❌ %s\n<b>%s</b>", renderDiff(got, expected), html.EscapeString(lang.Title(tag, word)))
+	}
+
+	_, next, err := uh.review(ctx, userID, *card, grade)
+	if err != nil {
+		entry.WithError(err).Error("failed to review word")
+		return err
+	}
+
+	text = fmt.Sprintf("%s\nNext review in %s", text, formatInterval(time.Until(next)))
+	if progress := uh.trackGoal(ctx, userID); progress != "" {
+		text += "\n" + html.EscapeString(progress)
+	}
+
+	msg := tgbotapi.NewMessage(userID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Next Word", TypeCommand),
+		),
+	)
+	if _, err = uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+}
+
+// HandleTypeSkip reveals the word the user was asked to type and counts it as
+// forgotten.
+func (uh *UpdateHandler) HandleTypeSkip(ctx context.Context, userID int64) error {
+	entry := logrus.WithFields(logrus.Fields{
+		"spot":    "UpdateHandler.HandleTypeSkip",
+		"user_id": userID,
+	})
+
+	var card *sessionCard
+	uh.states.update(userID, func(state *chatState) {
+		card, state.awaitingAnswer = state.awaitingAnswer, nil
+	})
+	if card == nil {
+		return nil
+	}
+
+	word := card.word
+	if _, _, err := uh.review(ctx, userID, *card, db.GradeAgain); err != nil {
+		entry.WithError(err).Error("failed to review word")
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(userID, uh.title(ctx, userID, word))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Next Word", TypeCommand),
+		),
+	)
+	if _, err := uh.updateFetcher.GetBot().Send(msg); err != nil {
+		entry.WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+}
+
+// renderDiff formats the diff of got and expected as telegram HTML. Extra
+// characters are struck through and missing ones are underlined.
+func renderDiff(got, expected string) string {
+	var sb strings.Builder
+	for _, op := range fuzzy.Diff(got, expected) {
+		text := html.EscapeString(op.Text)
+		switch op.Kind {
+		case fuzzy.Equal:
+			sb.WriteString(text)
+		case fuzzy.Delete:
+			sb.WriteString("<s>" + text + "</s>")
+		case fuzzy.Insert:
+			sb.WriteString("<u>" + text + "</u>")
+		}
+	}
+
+	return sb.String()
+}
@@ -17,6 +17,40 @@ const (
 	RandomCommand             string = "/random"
 	MeaningCommand            string = "/meaning"
 	MeaningWithExampleCommand string = "/meaning_with_example"
+	ExampleCommand            string = "/example"
+	GradeCommand              string = "/grade"
+	GradeReverseCommand       string = "/grade_reverse"
+	ReverseCommand            string = "/reverse"
+	RemindCommand             string = "/remind"
+	TimezoneCommand           string = "/timezone"
+	QuietCommand              string = "/quiet"
+	StatsCommand              string = "/stats"
+	GoalCommand               string = "/goal"
+	ReviewCommand             string = "/review"
+	ReviewMissedCommand       string = "/review_missed"
+	SchedulerCommand          string = "/scheduler"
+	QuizCommand               string = "/quiz"
+	QuizAnswerCommand         string = "/quiz_answer"
+	TypeCommand               string = "/type"
+	TypeSkipCommand           string = "/type_skip"
+	LimitsCommand             string = "/limits"
+	SuspendCommand            string = "/suspend"
+	UnsuspendCommand          string = "/unsuspend"
+	SuspendedCommand          string = "/suspended"
+	BuryCommand               string = "/bury"
+	KnownCommand              string = "/known"
+	LeechesCommand            string = "/leeches"
+	LeechAskCommand           string = "/leech_ask"
+	EditCommand               string = "/edit"
+	DeleteCommand             string = "/delete"
+	PurgeCommand              string = "/purge"
+	DuplicatesCommand         string = "/duplicates"
+	DecksCommand              string = "/decks"
+	SubscribeCommand          string = "/subscribe"
+	UnsubscribeCommand        string = "/unsubscribe"
+	ChatDeckCommand           string = "/chat_deck"
+	ShareCommand              string = "/share"
+	LanguagesCommand          string = "/languages"
 )
 
 var (
@@ -25,6 +59,29 @@ var (
 		RandomCommand:             "Gives you random word to answer",
 		MeaningCommand:            "find meaning of a word /meaning <word>",
 		MeaningWithExampleCommand: "gives an example for a word /meaning_with_example <word>",
+		SchedulerCommand:          "choose how words are scheduled /scheduler <name>",
+		QuizCommand:               "Asks a word with four meanings to choose from",
+		ReverseCommand:            "turn meaning to word cards on or off /reverse on|off [deck]",
+		RemindCommand:             "daily review reminder /remind <HH:MM>|off",
+		TimezoneCommand:           "set your timezone /timezone <Area/City>",
+		QuietCommand:              "hours without reminders /quiet <HH:MM-HH:MM>|off",
+		StatsCommand:              "Shows how your reviews are going, /stats chart for charts",
+		GoalCommand:               "set how many reviews you want to do every day /goal <reviews>|off",
+		ReviewCommand:             "review N cards in a row and get a summary /review [N]",
+		TypeCommand:               "Gives you a meaning to type the word for",
+		LimitsCommand:             "daily limits of new words and reviews /limits new|review <N>",
+		SuspendedCommand:          "lists suspended and buried words",
+		UnsuspendCommand:          "puts a suspended word back /unsuspend <word>",
+		LeechesCommand:            "practice the words you keep forgetting, /leeches threshold <N>|off to configure",
+		EditCommand:               "change a word you posted /edit <word>",
+		DeleteCommand:             "delete a word you posted /delete <word>",
+		DuplicatesCommand:         "what happens to words posted twice /duplicates reject|replace|append|homograph",
+		DecksCommand:              "lists the decks you can subscribe to",
+		SubscribeCommand:          "get the words of a deck /subscribe <deck>",
+		UnsubscribeCommand:        "stop getting the words of a deck /unsubscribe <deck>",
+		ChatDeckCommand:           "the deck words posted in a chat go to /chat_deck <deck>",
+		ShareCommand:              "share the words you post with everyone /share on|off",
+		LanguagesCommand:          "the languages of words posted in a chat and of their meanings /languages <word language> [meaning language]",
 	}
 )
 
@@ -33,10 +90,24 @@ type UpdateHandler struct {
 	wordsRepo     *db.WordsRepo
 	userWordsRepo *db.UserWordsRepo
 	usersRepo     *db.UsersRepo
+	reviewsRepo   *db.ReviewsRepo
+	chatsRepo     *db.ChatsRepo
+	decksRepo     *db.DecksRepo
+
+	states *chatStates
 }
 
-func NewUpdateHandler(uf *tgapi.UpdateFetcher, wordsRepo *db.WordsRepo, userWordsRepo *db.UserWordsRepo, usersRepo *db.UsersRepo) *UpdateHandler {
-	return &UpdateHandler{updateFetcher: uf, wordsRepo: wordsRepo, userWordsRepo: userWordsRepo, usersRepo: usersRepo}
+func NewUpdateHandler(uf *tgapi.UpdateFetcher, wordsRepo *db.WordsRepo, userWordsRepo *db.UserWordsRepo, usersRepo *db.UsersRepo, reviewsRepo *db.ReviewsRepo, chatsRepo *db.ChatsRepo, decksRepo *db.DecksRepo) *UpdateHandler {
+	return &UpdateHandler{
+		updateFetcher: uf,
+		wordsRepo:     wordsRepo,
+		userWordsRepo: userWordsRepo,
+		usersRepo:     usersRepo,
+		reviewsRepo:   reviewsRepo,
+		chatsRepo:     chatsRepo,
+		decksRepo:     decksRepo,
+		states:        newChatStates(),
+	}
 }
 
 func (uh *UpdateHandler) HandlerLoop(ctx context.Context) (err error) {
@@ -69,6 +140,15 @@ func (uh *UpdateHandler) HandlerLoop(ctx context.Context) (err error) {
 			msg = update.Message
 		} else if update.ChannelPost != nil {
 			msg = update.ChannelPost
+		} else if update.EditedMessage != nil || update.EditedChannelPost != nil {
+			msg = update.EditedMessage
+			if msg == nil {
+				msg = update.EditedChannelPost
+			}
+			if err := uh.HandleEditedPost(ctx, msg); err != nil {
+				entry.WithError(err).Error("failed to handle edited post")
+			}
+			continue
 		} else if update.CallbackQuery != nil {
 			msg = update.CallbackQuery.Message
 			msg.Text = update.CallbackQuery.Data
@@ -78,14 +158,225 @@ func (uh *UpdateHandler) HandlerLoop(ctx context.Context) (err error) {
 			continue
 		}
 
+		if update.Message != nil && msg.Text != "" && !strings.HasPrefix(msg.Text, "/") &&
+			uh.states.get(msg.Chat.ID).awaitingAnswer != nil {
+			if err := uh.HandleTypedAnswer(ctx, msg.Text, msg.Chat.ID); err != nil {
+				entry.WithError(err).Error("failed to handle typed answer")
+			}
+			continue
+		}
+
 		switch msg.Text {
 		case StartCommand:
-			_ = uh.HandleStart(ctx, msg.Chat.ID)
+			_ = uh.HandleStart(ctx, msg.Text, msg.Chat.ID)
 		case RandomCommand:
 			_ = uh.HandleRandom(ctx, msg.Chat.ID)
+		case QuizCommand:
+			_ = uh.HandleQuiz(ctx, msg.Chat.ID)
+		case TypeCommand:
+			_ = uh.HandleType(ctx, msg.Chat.ID)
+		case TypeSkipCommand:
+			_ = uh.HandleTypeSkip(ctx, msg.Chat.ID)
+		case ReviewMissedCommand:
+			_ = uh.HandleReviewMissed(ctx, msg.Chat.ID)
+		case SuspendedCommand:
+			_ = uh.HandleSuspended(ctx, msg.Chat.ID)
+		case LeechAskCommand:
+			_ = uh.HandleLeechAsk(ctx, msg.Chat.ID)
+		case DecksCommand:
+			_ = uh.HandleDecks(ctx, msg.Chat.ID)
 		//case TestCommand:
 		//	panic("this is a test")
 		default:
+			if strings.HasPrefix(msg.Text, StartCommand+" ") {
+				if err := uh.HandleStart(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle start command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, GradeCommand) {
+				if err := uh.HandleGrade(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle grade command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, QuizAnswerCommand) {
+				if err := uh.HandleQuizAnswer(ctx, msg.Text, msg.Chat.ID, msg.MessageID); err != nil {
+					entry.WithError(err).Error("failed to handle quiz answer")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, StatsCommand) {
+				if err := uh.HandleStats(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle stats command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, ReviewCommand) {
+				if err := uh.HandleReview(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle review command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, GoalCommand) {
+				if err := uh.HandleGoal(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle goal command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, RemindCommand) {
+				if err := uh.HandleRemind(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle remind command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, TimezoneCommand) {
+				if err := uh.HandleTimezone(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle timezone command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, QuietCommand) {
+				if err := uh.HandleQuiet(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle quiet command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, ReverseCommand) {
+				if err := uh.HandleReverse(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle reverse command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, SchedulerCommand) {
+				if err := uh.HandleScheduler(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle scheduler command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, ExampleCommand) {
+				if err := uh.HandleExample(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle example command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, SuspendCommand) {
+				if err := uh.HandleSuspend(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle suspend command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, UnsuspendCommand) {
+				if err := uh.HandleUnsuspend(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle unsuspend command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, BuryCommand) {
+				if err := uh.HandleBury(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle bury command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, KnownCommand) {
+				if err := uh.HandleKnown(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle known command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, LeechesCommand) {
+				if err := uh.HandleLeeches(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle leeches command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, EditCommand) {
+				if err := uh.HandleEdit(ctx, msg.Text, msg.Chat.ID, senderOf(update, msg)); err != nil {
+					entry.WithError(err).Error("failed to handle edit command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, DeleteCommand) {
+				if err := uh.HandleDelete(ctx, msg.Text, msg.Chat.ID, senderOf(update, msg)); err != nil {
+					entry.WithError(err).Error("failed to handle delete command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, PurgeCommand) {
+				if err := uh.HandlePurge(ctx, msg.Text, msg.Chat.ID, senderOf(update, msg)); err != nil {
+					entry.WithError(err).Error("failed to handle purge command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, DuplicatesCommand) {
+				if err := uh.HandleDuplicates(ctx, msg.Text, msg.Chat.ID, senderOf(update, msg)); err != nil {
+					entry.WithError(err).Error("failed to handle duplicates command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, SubscribeCommand) {
+				if err := uh.HandleSubscribe(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle subscribe command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, UnsubscribeCommand) {
+				if err := uh.HandleUnsubscribe(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle unsubscribe command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, ChatDeckCommand) {
+				if err := uh.HandleChatDeck(ctx, msg.Text, msg.Chat.ID, senderOf(update, msg)); err != nil {
+					entry.WithError(err).Error("failed to handle chat deck command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, ShareCommand) {
+				if err := uh.HandleShare(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle share command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, LanguagesCommand) {
+				if err := uh.HandleLanguages(ctx, msg.Text, msg.Chat.ID, senderOf(update, msg)); err != nil {
+					entry.WithError(err).Error("failed to handle languages command")
+				}
+				continue
+			}
+
+			if strings.HasPrefix(msg.Text, LimitsCommand) {
+				if err := uh.HandleLimits(ctx, msg.Text, msg.Chat.ID); err != nil {
+					entry.WithError(err).Error("failed to handle limits command")
+				}
+				continue
+			}
+
 			if strings.Contains(msg.Text, MeaningCommand) {
 				if err := uh.HandleMeaning(ctx, msg.Text, msg.Chat.ID); err != nil {
 					entry.WithError(err).Error("failed to handle meaning command")
@@ -93,16 +384,36 @@ func (uh *UpdateHandler) HandlerLoop(ctx context.Context) (err error) {
 				continue
 			}
 
-			// TODO handle words without a meaning
-			if len(msg.Photo) == 0 {
+			if len(msg.Photo) > 0 || (msg.Text != "" && !strings.HasPrefix(msg.Text, "/")) {
+				uh.rememberChat(ctx, msg.Chat)
+			}
+
+			if len(msg.Photo) > 0 {
+				if err := uh.HandleInsert(ctx, msg.Chat.ID, msg.MessageID, msg.Caption, msg.Photo[len(msg.Photo)-1].FileID); err != nil {
+					entry.WithError(err).Error("failed to insert a new word")
+				}
 				continue
 			}
 
-			if err := uh.HandleInsert(ctx, msg.Caption, msg.Photo[len(msg.Photo)-1].FileID); err != nil {
-				entry.WithError(err).Error("failed to insert a new word")
+			if msg.Text != "" && !strings.HasPrefix(msg.Text, "/") {
+				if err := uh.HandleBulkInsert(ctx, msg.Chat.ID, msg.MessageID, msg.Text); err != nil {
+					entry.WithError(err).Error("failed to insert new words")
+				}
 			}
 		}
 	}
 
 	return nil
 }
+
+func (uh *UpdateHandler) sendText(chatID int64, text string) error {
+	if _, err := uh.updateFetcher.GetBot().Send(tgbotapi.NewMessage(chatID, text)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"spot":    "UpdateHandler.sendText",
+			"chat_id": chatID,
+		}).WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+}
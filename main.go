@@ -6,6 +6,8 @@ import (
 	"flag"
 	"github.com/itzloop/langhelperbot/internal/langhelper/backup_handler"
 	"github.com/itzloop/langhelperbot/internal/langhelper/db"
+	"github.com/itzloop/langhelperbot/internal/langhelper/lang"
+	"github.com/itzloop/langhelperbot/internal/langhelper/reminder_handler"
 	"github.com/itzloop/langhelperbot/internal/langhelper/update_handlers"
 	"github.com/itzloop/langhelperbot/internal/tgapi"
 	"github.com/joho/godotenv"
@@ -18,6 +20,7 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 )
 
 type Update struct {
@@ -47,7 +50,9 @@ func main() {
 	dbPath = flag.String("db-path", "/data/sqlite.db", "Path to the sqlite3 db file")
 	backupReceiver := flag.Int64("backup-receiver", 0, "Telegram userID to send backup to")
 	backupInterval := flag.Duration("backup-interval", 24*time.Hour, "Interval to backup")
+	reminderInterval := flag.Duration("reminder-interval", time.Minute, "How often to check for users to remind")
 	backup := flag.Bool("backup", false, "Send sqlite db backup to an specified user in Telegram. Needs backup-receiver to be specified")
+	foldDiacritics := flag.Bool("fold-diacritics", false, "Take words that only differ in diacritics, like café and cafe, for the same word")
 	flag.Parse()
 
 	wd, err := os.Getwd()
@@ -92,6 +97,7 @@ func main() {
 		logrus.WithError(err).Fatalln("failed to connect to db")
 	}
 
+	lang.FoldDiacritics = *foldDiacritics
 	wordsRepo, err := db.NewWordsRepo(sqlDB)
 	if err != nil {
 		logrus.WithError(err).Fatalln("failed to create WordsRepo")
@@ -106,7 +112,40 @@ func main() {
 	if err != nil {
 		logrus.WithError(err).Fatalln("failed to create UsersRepo")
 	}
-	uh := update_handlers.NewUpdateHandler(uf, wordsRepo, userWordsRepo, usersRepo)
+
+	reviewsRepo, err := db.NewReviewsRepo(sqlDB)
+	if err != nil {
+		logrus.WithError(err).Fatalln("failed to create ReviewsRepo")
+	}
+
+	chatsRepo, err := db.NewChatsRepo(sqlDB)
+	if err != nil {
+		logrus.WithError(err).Fatalln("failed to create ChatsRepo")
+	}
+
+	// decks are made from the words and users above, so this comes last.
+	decksRepo, err := db.NewDecksRepo(sqlDB)
+	if err != nil {
+		logrus.WithError(err).Fatalln("failed to create DecksRepo")
+	}
+
+	if err = wordsRepo.Rekey(ctx); err != nil {
+		logrus.WithError(err).Fatalln("failed to rekey words")
+	}
+
+	collisions, err := wordsRepo.Collisions(ctx)
+	if err != nil {
+		logrus.WithError(err).Fatalln("failed to find words with the same key")
+	}
+	for _, c := range collisions {
+		logrus.WithFields(logrus.Fields{
+			"owner": c.Owner,
+			"key":   c.Key,
+			"words": c.Words,
+		}).Warn("words are the same word typed differently, only the first is found, edit or delete the others")
+	}
+
+	uh := update_handlers.NewUpdateHandler(uf, wordsRepo, userWordsRepo, usersRepo, reviewsRepo, chatsRepo, decksRepo)
 
 	g.Go(func() error {
 		return uf.Start(gCtx)
@@ -116,6 +155,11 @@ func main() {
 		return uh.HandlerLoop(gCtx)
 	})
 
+	rh := reminder_handler.NewReminderHandler(*reminderInterval, uf, usersRepo, uh.CountDue, uh.HandleRandom)
+	g.Go(func() error {
+		return rh.Start(gCtx)
+	})
+
 	if *backup {
 		if *backupReceiver == 0 {
 			logrus.Fatalln("backup-receiver must be set with -backup flag.")